@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"add_vibe_article/articlesjson"
+)
+
+// pagePlaceholder is replaced by the page number in the URL template of -api-index
+const pagePlaceholder = "{page}"
+
+// jsonAPIItemURLFields are the keys the URL of an item of a JSON index is read from, in order of preference
+var jsonAPIItemURLFields = []string{"url", "link", "permalink", "canonical_url", "canonicalUrl"}
+
+// jsonAPIListKeys are keys under which index APIs commonly nest the list of items
+var jsonAPIListKeys = []string{"items", "data", "posts", "articles", "results", "entries"}
+
+// apiIndexPageCap bounds the pages read by -api-index with -api-max-pages 0, in case an API
+// never runs out of pages
+const apiIndexPageCap = 1000
+
+// extractAPIIndex reads a paginated JSON index, fetching the pages 1, 2, ... of the URL template
+// until a page has no items or -api-max-pages pages were read, and maps each item to an article
+// like -prefer-json-api does. Items without a URL are skipped. Some APIs ignore an unknown page
+// parameter and return the same items for every page, so a page without any new URL ends the
+// index too.
+func extractAPIIndex(ctx context.Context, template string, opts Options) ([]OGMetadata, error) {
+	maxPages := opts.APIMaxPages
+	if maxPages <= 0 {
+		maxPages = apiIndexPageCap
+	}
+
+	var articles []OGMetadata
+	seen := map[string]bool{}
+	for page := 1; page <= maxPages; page++ {
+		pageURL := strings.ReplaceAll(template, pagePlaceholder, strconv.Itoa(page))
+		items, err := fetchAPIIndexPage(ctx, pageURL, page, opts)
+		if err != nil {
+			return nil, fmt.Errorf("page %d: %w", page, err)
+		}
+		if len(items) == 0 {
+			return articles, nil
+		}
+
+		newURLs := 0
+		for i, item := range items {
+			itemURL := jsonAPIString(item, jsonAPIItemURLFields)
+			if itemURL == "" {
+				warnf("%s: skipping item %d, which has no URL", pageURL, i+1)
+				continue
+			}
+			itemURL = resolveURL(pageURL, itemURL)
+			key := articlesjson.CanonicalizeURL(itemURL, opts.FoldURLCase)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			newURLs++
+
+			metadata, err := jsonAPIObjectArticle(item, itemURL, opts)
+			if err != nil {
+				warnf("%s: skipping item %d: %v", pageURL, i+1, err)
+				continue
+			}
+			articles = append(articles, metadata)
+		}
+
+		if newURLs == 0 {
+			if !opts.Quiet {
+				fmt.Printf("Stopped reading %s at page %d, which has no new URLs\n", template, page)
+			}
+			return articles, nil
+		}
+	}
+
+	if !opts.Quiet {
+		fmt.Printf("Stopped reading %s after %d page(s) (-api-max-pages)\n", template, maxPages)
+	}
+	return articles, nil
+}
+
+// fetchAPIIndexPage returns the items of a page of a JSON index: the response itself when it is
+// a list, or the list under one of the common keys. Past the first page, a 400 or 404 response
+// also ends the index, since some APIs, such as WordPress, reject page numbers past the last one.
+func fetchAPIIndexPage(ctx context.Context, pageURL string, page int, opts Options) ([]map[string]interface{}, error) {
+	resp, err := fetchPage(ctx, pageURL, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if page > 1 && (resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusNotFound) {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch URL: status code %d", resp.StatusCode)
+	}
+
+	body, truncated, err := readResponse(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if truncated {
+		return nil, fmt.Errorf("JSON API response is larger than %d MB", maxResponseBytes>>20)
+	}
+
+	var data interface{}
+	err = json.Unmarshal(body, &data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON API response: %w", err)
+	}
+
+	if object, ok := data.(map[string]interface{}); ok {
+		data = nil
+		for _, key := range jsonAPIListKeys {
+			if list, ok := object[key].([]interface{}); ok {
+				data = list
+				break
+			}
+		}
+	}
+	list, ok := data.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("JSON API response has no list of items")
+	}
+
+	var items []map[string]interface{}
+	for _, element := range list {
+		if item, ok := element.(map[string]interface{}); ok {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
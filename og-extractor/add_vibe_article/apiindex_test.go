@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestExtractAPIIndexStops(t *testing.T) {
+	page := func(slugs ...string) fixture {
+		var items []string
+		for _, slug := range slugs {
+			items = append(items, fmt.Sprintf(`{"link": "/blog/%s", "title": "%s"}`, slug, slug))
+		}
+		return fixture{
+			Body:   "[" + strings.Join(items, ",") + "]",
+			Header: http.Header{"Content-Type": {"application/json"}},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		pages     []fixture
+		maxPages  int
+		wantSlugs []string
+		wantFetch int
+	}{
+		{
+			name:      "empty page",
+			pages:     []fixture{page("one", "two"), page("three"), page()},
+			wantSlugs: []string{"one", "two", "three"},
+			wantFetch: 3,
+		},
+		{
+			name:      "page number ignored",
+			pages:     []fixture{page("one", "two"), page("one", "two"), page("one", "two")},
+			wantSlugs: []string{"one", "two"},
+			wantFetch: 2,
+		},
+		{
+			name:      "overlapping pages",
+			pages:     []fixture{page("one", "two"), page("two", "three"), page("three")},
+			wantSlugs: []string{"one", "two", "three"},
+			wantFetch: 3,
+		},
+		{
+			name:      "max pages",
+			pages:     []fixture{page("one"), page("two"), page("three")},
+			maxPages:  2,
+			wantSlugs: []string{"one", "two"},
+			wantFetch: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFixtureServer(t)
+			for i, f := range tt.pages {
+				server.handle(fmt.Sprintf("/api/%d", i+1), f)
+			}
+
+			articles, err := extractAPIIndex(context.Background(), server.URL+"/api/{page}",
+				Options{Quiet: true, APIMaxPages: tt.maxPages})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var slugs []string
+			for _, article := range articles {
+				slugs = append(slugs, article.Title)
+			}
+			if strings.Join(slugs, ",") != strings.Join(tt.wantSlugs, ",") {
+				t.Errorf("articles = %v, want %v", slugs, tt.wantSlugs)
+			}
+
+			fetched := 0
+			for i := range tt.pages {
+				fetched += len(server.requestsTo(fmt.Sprintf("/api/%d", i+1)))
+			}
+			if fetched != tt.wantFetch {
+				t.Errorf("fetched %d page(s), want %d", fetched, tt.wantFetch)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strings"
+)
+
+// waybackAvailabilityURL is the Wayback Machine API returning the latest snapshot of a URL
+var waybackAvailabilityURL = "https://archive.org/wayback/available"
+
+// waybackSnapshot is a snapshot described by the availability API
+type waybackSnapshot struct {
+	Available bool   `json:"available"`
+	URL       string `json:"url"`
+	Timestamp string `json:"timestamp"`
+	Status    string `json:"status"`
+}
+
+// findWaybackSnapshot asks the Wayback Machine for the latest snapshot of the URL
+func findWaybackSnapshot(ctx context.Context, url string, opts Options) (waybackSnapshot, error) {
+	apiURL := waybackAvailabilityURL + "?url=" + neturl.QueryEscape(url)
+	resp, err := fetchPage(ctx, apiURL, opts)
+	if err != nil {
+		return waybackSnapshot{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return waybackSnapshot{}, fmt.Errorf("availability API: status code %d", resp.StatusCode)
+	}
+
+	var availability struct {
+		ArchivedSnapshots struct {
+			Closest *waybackSnapshot `json:"closest"`
+		} `json:"archived_snapshots"`
+	}
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&availability)
+	if err != nil {
+		return waybackSnapshot{}, fmt.Errorf("availability API: %w", err)
+	}
+
+	snapshot := availability.ArchivedSnapshots.Closest
+	if snapshot == nil || !snapshot.Available || snapshot.URL == "" {
+		return waybackSnapshot{}, fmt.Errorf("no snapshot available")
+	}
+	if snapshot.Status != "" && snapshot.Status != "200" {
+		return waybackSnapshot{}, fmt.Errorf("the latest snapshot has status %s", snapshot.Status)
+	}
+	return *snapshot, nil
+}
+
+// rawSnapshotURL returns the URL of the snapshot as it was captured. The "id_" flag after the
+// timestamp turns off the rewriting of its links to the archive, so og:url, og:image and the
+// canonical link keep pointing to the original site.
+func rawSnapshotURL(snapshot waybackSnapshot) string {
+	if snapshot.Timestamp == "" {
+		return snapshot.URL
+	}
+	return strings.Replace(snapshot.URL, "/"+snapshot.Timestamp+"/", "/"+snapshot.Timestamp+"id_/", 1)
+}
+
+// extractFromArchive extracts the articles of a URL from its latest Wayback Machine snapshot,
+// recording the snapshot in each article
+func extractFromArchive(ctx context.Context, url string, opts Options) ([]OGMetadata, error) {
+	snapshot, err := findWaybackSnapshot(ctx, url, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	articles, err := extractArticlesFrom(ctx, rawSnapshotURL(snapshot), url, opts)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", snapshot.URL, err)
+	}
+
+	for i := range articles {
+		articles[i].ArchivedFrom = snapshot.URL
+	}
+	return articles, nil
+}
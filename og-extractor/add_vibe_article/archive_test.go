@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestViaArchive(t *testing.T) {
+	server := newFixtureServer(t)
+	gone := server.URL + "/blog/gone"
+	snapshot := server.URL + "/web/20240315093000/" + gone
+	server.handle("/web/20240315093000id_/"+gone, fixture{File: "article.html"})
+
+	availability := server.handle("/wayback/available", fixture{
+		Body: `{"url": "` + gone + `", "archived_snapshots": {"closest": ` +
+			`{"available": true, "url": "` + snapshot + `", "timestamp": "20240315093000", "status": "200"}}}`,
+		Header: http.Header{"Content-Type": {"application/json"}},
+	})
+	defaultAvailabilityURL := waybackAvailabilityURL
+	waybackAvailabilityURL = availability
+	t.Cleanup(func() { waybackAvailabilityURL = defaultAvailabilityURL })
+
+	tests := []struct {
+		name       string
+		viaArchive bool
+		wantErr    string
+	}{
+		{"live failure", false, "status code 404"},
+		{"archived copy", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := writeArticles(t, "articles.json")
+			articles, err := processURL(context.Background(), gone, output, Options{Quiet: true, Mode: "append", ViaArchive: tt.viaArchive})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(articles) != 1 {
+				t.Fatalf("got %d articles, want 1", len(articles))
+			}
+			article := articles[0]
+			if article.Title != "Building a Blog with Vibe Coding" {
+				t.Errorf("title = %q, want the one of the snapshot", article.Title)
+			}
+			if article.Slug != "gone" {
+				t.Errorf("slug = %q, want the one of the live URL", article.Slug)
+			}
+			if article.ArchivedFrom != snapshot {
+				t.Errorf("archivedFrom = %q, want %q", article.ArchivedFrom, snapshot)
+			}
+
+			requests := server.requestsTo("/wayback/available")
+			if len(requests) == 0 || requests[len(requests)-1].URL.Query().Get("url") != gone {
+				t.Errorf("the availability API wasn't asked for %s", gone)
+			}
+		})
+	}
+}
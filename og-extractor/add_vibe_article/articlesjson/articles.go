@@ -0,0 +1,80 @@
+// Package articlesjson reads and writes the articles.json collection of vibe coding articles, in
+// either of its layouts, and adds articles to it the way og-extractor does. It works on
+// io.Reader and io.Writer, so callers control where the collection is stored.
+package articlesjson
+
+// OGMetadata struct to store Open Graph metadata
+type OGMetadata struct {
+	URL            string                     `json:"url"`
+	Title          string                     `json:"title"`
+	Description    string                     `json:"description"`
+	Type           string                     `json:"type,omitempty"`
+	Locale         string                     `json:"locale,omitempty"`
+	Image          string                     `json:"image"`
+	ImageType      string                     `json:"imageType,omitempty"`
+	ImageObjects   []ImageInfo                `json:"imageObjects,omitempty"`
+	Video          string                     `json:"video,omitempty"`
+	VideoDuration  int                        `json:"videoDuration,omitempty"`
+	VideoWidth     int                        `json:"videoWidth,omitempty"`
+	VideoHeight    int                        `json:"videoHeight,omitempty"`
+	Slug           string                     `json:"slug"`
+	PublishDate    string                     `json:"publishDate,omitempty"`
+	Source         string                     `json:"source,omitempty"`
+	PublisherLogo  string                     `json:"publisherLogo,omitempty"`
+	Author         string                     `json:"author,omitempty"`
+	Tags           []string                   `json:"tags,omitempty"`
+	Category       string                     `json:"category,omitempty"`
+	OriginalURL    string                     `json:"originalUrl,omitempty"`
+	OriginalSource string                     `json:"originalSource,omitempty"`
+	OriginalDate   string                     `json:"originalDate,omitempty"`
+	CanonicalURL   string                     `json:"canonicalUrl,omitempty"`
+	AlternateURLs  []string                   `json:"alternateUrls,omitempty"`
+	NextURL        string                     `json:"nextUrl,omitempty"`
+	ArchivedFrom   string                     `json:"archivedFrom,omitempty"`
+	PrevURL        string                     `json:"prevUrl,omitempty"`
+	AuthorURL      string                     `json:"authorUrl,omitempty"`
+	TwitterSite    string                     `json:"twitterSite,omitempty"`
+	TwitterCreator string                     `json:"twitterCreator,omitempty"`
+	Paywalled      bool                       `json:"paywalled,omitempty"`
+	WordCount      int                        `json:"wordCount,omitempty"`
+	Extras         map[string]string          `json:"extras,omitempty"`
+	Localized      map[string]LocalizedText   `json:"localized,omitempty"`
+	Meta           *ExtractionMeta            `json:"meta,omitempty"`
+	Confidence     map[string]FieldConfidence `json:"confidence,omitempty"`
+}
+
+// ImageInfo describes one og:image block with its sub-properties
+type ImageInfo struct {
+	URL    string `json:"url"`
+	Alt    string `json:"alt,omitempty"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+	Type   string `json:"type,omitempty"`
+}
+
+// LocalizedText is the title and description of an article in one language
+type LocalizedText struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+// ExtractionMeta records how the metadata was obtained
+type ExtractionMeta struct {
+	FetchedAt string `json:"fetchedAt"`
+	Lang      string `json:"lang,omitempty"`
+	Pass      string `json:"pass,omitempty"`
+}
+
+// FieldConfidence records where a field was obtained and how much it can be trusted
+type FieldConfidence struct {
+	Source string  `json:"source"`
+	Score  float64 `json:"score"`
+}
+
+// ArticlesCollection represents the structure of the target JSON file
+type ArticlesCollection struct {
+	Articles []OGMetadata `json:"articles"`
+
+	// Whether the file groups the articles by source (see layout.go)
+	Grouped bool `json:"-"`
+}
@@ -0,0 +1,232 @@
+package articlesjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Options controls how an article is added to a collection and how the collection is written.
+// The zero value appends the article and writes each JSON value on a line of its own, unindented.
+type Options struct {
+	// Indent is the indentation of each level of the written JSON, such as two spaces
+	Indent string
+
+	// Replace drops the existing articles, keeping the layout of the collection
+	Replace bool
+
+	// GroupBySource writes the collection grouped by source, {"sources": {"Site": [...]}}
+	GroupBySource bool
+
+	// Update replaces the article with the same slug instead of appending a duplicate, and
+	// MergeTags then keeps the tags of the replaced article, adding the new ones to them
+	Update    bool
+	MergeTags bool
+
+	// MergeByTitle records an article whose title matches an existing one as an alternate URL
+	// of it instead of adding a duplicate
+	MergeByTitle bool
+
+	// DisambiguateSlugs gives an article whose slug is already used by another URL a unique slug
+	DisambiguateSlugs bool
+
+	// Limit keeps only the most recent articles when greater than zero
+	Limit int
+
+	// Warnf and Printf, when set, are given the warnings (such as slug collisions) and the notes
+	// (such as a slug changed or an alternate URL recorded) about the changes to the collection
+	Warnf  func(format string, args ...interface{})
+	Printf func(format string, args ...interface{})
+}
+
+func (opts Options) warnf(format string, args ...interface{}) {
+	if opts.Warnf != nil {
+		opts.Warnf(format, args...)
+	}
+}
+
+func (opts Options) printf(format string, args ...interface{}) {
+	if opts.Printf != nil {
+		opts.Printf(format, args...)
+	}
+}
+
+// ReadCollection reads an articles collection in either layout. Empty input is an empty collection.
+func ReadCollection(r io.Reader) (ArticlesCollection, error) {
+	collection := ArticlesCollection{
+		Articles: []OGMetadata{},
+	}
+
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return collection, fmt.Errorf("failed to read existing file: %w", err)
+	}
+	if len(bytes.TrimSpace(content)) == 0 {
+		return collection, nil
+	}
+
+	err = json.Unmarshal(content, &collection)
+	if err != nil {
+		return collection, fmt.Errorf("invalid JSON format in existing file: %w", err)
+	}
+	return collection, nil
+}
+
+// WriteCollection writes an articles collection as JSON, in its layout and with the
+// indentation of the options, ending with a newline
+func WriteCollection(w io.Writer, collection ArticlesCollection, opts Options) error {
+	jsonData, err := json.MarshalIndent(collection, "", opts.Indent)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	// End the file with a single newline like most editors and tools expect
+	_, err = w.Write(append(jsonData, '\n'))
+	if err != nil {
+		return fmt.Errorf("failed to write collection: %w", err)
+	}
+	return nil
+}
+
+// AppendArticle reads the collection from existing, which may be nil for a new collection, adds
+// the article to it with Add and writes the resulting collection to w
+func AppendArticle(existing io.Reader, w io.Writer, metadata OGMetadata, opts Options) error {
+	if existing == nil {
+		existing = strings.NewReader("")
+	}
+	collection, err := ReadCollection(existing)
+	if err != nil {
+		return err
+	}
+
+	collection.Add(metadata, opts)
+	return WriteCollection(w, collection, opts)
+}
+
+// Add adds the article to the collection and applies the limit, returning the articles it removed
+func (collection *ArticlesCollection) Add(metadata OGMetadata, opts Options) []OGMetadata {
+	if opts.Replace {
+		collection.Articles = []OGMetadata{}
+	}
+	if opts.GroupBySource {
+		collection.Grouped = true
+	}
+
+	// Another article may already use the slug, in which case both are kept under unique slugs
+	checkSlugCollision(collection.Articles, &metadata, opts)
+
+	// Replace the existing article when updating, record a repost under the same title as an
+	// alternate URL, otherwise append new metadata to articles array
+	switch {
+	case opts.Update && updateArticle(collection.Articles, metadata, opts.MergeTags):
+	case opts.MergeByTitle && addAlternateURL(collection.Articles, metadata, opts):
+	default:
+		collection.Articles = append(collection.Articles, metadata)
+	}
+
+	// Keep only the most recent articles if a limit is set
+	var removed []OGMetadata
+	if opts.Limit > 0 {
+		SortByDate(collection.Articles)
+		if len(collection.Articles) > opts.Limit {
+			removed = collection.Articles[opts.Limit:]
+			collection.Articles = collection.Articles[:opts.Limit]
+		}
+	}
+
+	return removed
+}
+
+// nonSlugCharacters matches the runs of characters of a host name that don't belong in a slug
+var nonSlugCharacters = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// checkSlugCollision detects an existing article with the same slug but a different URL, as
+// happens with generic last segments such as "index". Such a collision is reported and, with
+// DisambiguateSlugs, the article is given a unique slug: its host appended to the slug when the
+// hosts differ, otherwise a number. An article imported again, even under a variant of its URL such
+// as one with a trailing slash, gets back the slug it got the first time.
+func checkSlugCollision(articles []OGMetadata, metadata *OGMetadata, opts Options) {
+	if metadata.URL == "" {
+		return
+	}
+	for _, article := range articles {
+		if CanonicalizeURL(article.URL, false) == CanonicalizeURL(metadata.URL, false) && (article.Slug == metadata.Slug || opts.DisambiguateSlugs && strings.HasPrefix(article.Slug, metadata.Slug+"-")) {
+			metadata.Slug = article.Slug
+			return
+		}
+	}
+
+	var other *OGMetadata
+	taken := map[string]bool{}
+	for i, article := range articles {
+		taken[article.Slug] = true
+		if other == nil && article.Slug == metadata.Slug && article.URL != "" {
+			other = &articles[i]
+		}
+	}
+	if other == nil {
+		return
+	}
+	if !opts.DisambiguateSlugs {
+		opts.warnf("slug %s of %s is already used by %s, use -disambiguate-slugs to keep both", metadata.Slug, metadata.URL, other.URL)
+		return
+	}
+
+	slug := metadata.Slug
+	if !SameHost(metadata.URL, other.URL) {
+		if parsedURL, err := url.Parse(metadata.URL); err == nil {
+			host := strings.TrimPrefix(strings.ToLower(parsedURL.Hostname()), "www.")
+			slug = metadata.Slug + "-" + strings.Trim(nonSlugCharacters.ReplaceAllString(host, "-"), "-")
+		}
+	}
+	for n := 2; taken[slug]; n++ {
+		slug = fmt.Sprintf("%s-%d", metadata.Slug, n)
+	}
+
+	opts.printf("Slug %s is already used by %s, storing %s as %s\n", metadata.Slug, other.URL, metadata.URL, slug)
+	metadata.Slug = slug
+}
+
+// updateArticle replaces the article with the same slug, returning false if there is none.
+// With keepTags, the tags of the existing article are kept and the new ones added to them.
+func updateArticle(articles []OGMetadata, metadata OGMetadata, keepTags bool) bool {
+	for i, article := range articles {
+		if article.Slug != metadata.Slug {
+			continue
+		}
+		if keepTags {
+			metadata.Tags = MergeTags(article.Tags, metadata.Tags)
+		}
+		articles[i] = metadata
+		return true
+	}
+	return false
+}
+
+// addAlternateURL records the URL of the article as an alternate URL of the existing article with
+// the same title (compared after normalization), returning false if there is none
+func addAlternateURL(articles []OGMetadata, metadata OGMetadata, opts Options) bool {
+	title := NormalizeTitle(metadata.Title)
+	if title == "" || metadata.URL == "" {
+		return false
+	}
+
+	for i, article := range articles {
+		if NormalizeTitle(article.Title) != title {
+			continue
+		}
+		// The URL may be a variant of one already recorded, such as one with a trailing slash
+		known := append([]string{article.URL}, article.AlternateURLs...)
+		if !slices.ContainsFunc(known, func(u string) bool { return CanonicalizeURL(u, false) == CanonicalizeURL(metadata.URL, false) }) {
+			articles[i].AlternateURLs = append(articles[i].AlternateURLs, metadata.URL)
+		}
+		opts.printf("Recorded %s as an alternate URL of %s (same title)\n", metadata.URL, article.Slug)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,183 @@
+package articlesjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAppendArticle(t *testing.T) {
+	article := OGMetadata{
+		URL:         "https://blog.example/post",
+		Title:       "A Post",
+		Slug:        "post",
+		PublishDate: "2024-05-06T07:08:09Z",
+		Source:      "Blog",
+		Tags:        []string{"go"},
+	}
+
+	tests := []struct {
+		name     string
+		existing string
+		opts     Options
+		want     string
+	}{
+		{
+			name: "new collection",
+			want: `{"articles":[{"url":"https://blog.example/post","title":"A Post","description":"","image":"","slug":"post","publishDate":"2024-05-06T07:08:09Z","source":"Blog","tags":["go"]}]}`,
+		},
+		{
+			name:     "appended to the existing articles",
+			existing: `{"articles":[{"url":"https://blog.example/old","title":"Old","description":"","image":"","slug":"old"}]}`,
+			want:     `{"articles":[{"url":"https://blog.example/old","title":"Old","description":"","image":"","slug":"old"},{"url":"https://blog.example/post","title":"A Post","description":"","image":"","slug":"post","publishDate":"2024-05-06T07:08:09Z","source":"Blog","tags":["go"]}]}`,
+		},
+		{
+			name:     "grouped layout kept",
+			existing: `{"sources":{"Other":[{"url":"https://other.example/a","title":"A","description":"","image":"","slug":"a","source":"Other"}]}}`,
+			want:     `{"sources":{"Blog":[{"url":"https://blog.example/post","title":"A Post","description":"","image":"","slug":"post","publishDate":"2024-05-06T07:08:09Z","source":"Blog","tags":["go"]}],"Other":[{"url":"https://other.example/a","title":"A","description":"","image":"","slug":"a","source":"Other"}]}}`,
+		},
+		{
+			name:     "grouped by source",
+			existing: `{"articles":[]}`,
+			opts:     Options{GroupBySource: true},
+			want:     `{"sources":{"Blog":[{"url":"https://blog.example/post","title":"A Post","description":"","image":"","slug":"post","publishDate":"2024-05-06T07:08:09Z","source":"Blog","tags":["go"]}]}}`,
+		},
+		{
+			name:     "updated with merged tags",
+			existing: `{"articles":[{"url":"https://blog.example/post","title":"Old","description":"","image":"","slug":"post","tags":["Web"]}]}`,
+			opts:     Options{Update: true, MergeTags: true},
+			want:     `{"articles":[{"url":"https://blog.example/post","title":"A Post","description":"","image":"","slug":"post","publishDate":"2024-05-06T07:08:09Z","source":"Blog","tags":["Web","go"]}]}`,
+		},
+		{
+			name:     "replaced",
+			existing: `{"articles":[{"url":"https://blog.example/old","title":"Old","description":"","image":"","slug":"old"}]}`,
+			opts:     Options{Replace: true},
+			want:     `{"articles":[{"url":"https://blog.example/post","title":"A Post","description":"","image":"","slug":"post","publishDate":"2024-05-06T07:08:09Z","source":"Blog","tags":["go"]}]}`,
+		},
+		{
+			name:     "limited to the most recent",
+			existing: `{"articles":[{"url":"https://blog.example/old","title":"Old","description":"","image":"","slug":"old","publishDate":"2020-01-01"}]}`,
+			opts:     Options{Limit: 1},
+			want:     `{"articles":[{"url":"https://blog.example/post","title":"A Post","description":"","image":"","slug":"post","publishDate":"2024-05-06T07:08:09Z","source":"Blog","tags":["go"]}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A new collection has no existing reader
+			var existing io.Reader
+			if tt.existing != "" {
+				existing = strings.NewReader(tt.existing)
+			}
+
+			var output bytes.Buffer
+			err := AppendArticle(existing, &output, article, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var compacted bytes.Buffer
+			if err := json.Compact(&compacted, output.Bytes()); err != nil {
+				t.Fatalf("AppendArticle() wrote invalid JSON: %v", err)
+			}
+			if compacted.String() != tt.want {
+				t.Errorf("AppendArticle() wrote\n%s\nwant\n%s", compacted.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCollectionIndent(t *testing.T) {
+	collection := ArticlesCollection{Articles: []OGMetadata{{URL: "https://blog.example/post", Slug: "post"}}}
+	tests := []struct {
+		indent string
+		want   string
+	}{
+		{"  ", "{\n  \"articles\": [\n    {\n      \"url\": \"https://blog.example/post\",\n      \"title\": \"\",\n      \"description\": \"\",\n      \"image\": \"\",\n      \"slug\": \"post\"\n    }\n  ]\n}\n"},
+		{"\t", "{\n\t\"articles\": [\n\t\t{\n\t\t\t\"url\": \"https://blog.example/post\",\n\t\t\t\"title\": \"\",\n\t\t\t\"description\": \"\",\n\t\t\t\"image\": \"\",\n\t\t\t\"slug\": \"post\"\n\t\t}\n\t]\n}\n"},
+	}
+
+	for _, tt := range tests {
+		var output bytes.Buffer
+		if err := WriteCollection(&output, collection, Options{Indent: tt.indent}); err != nil {
+			t.Fatal(err)
+		}
+		if output.String() != tt.want {
+			t.Errorf("WriteCollection() with indent %q wrote\n%s\nwant\n%s", tt.indent, output.String(), tt.want)
+		}
+	}
+}
+
+func TestAppendArticleInvalidJSON(t *testing.T) {
+	var output bytes.Buffer
+	err := AppendArticle(strings.NewReader(`{"articles": [`), &output, OGMetadata{Slug: "post"}, Options{})
+	if err == nil {
+		t.Fatal("AppendArticle() accepted invalid JSON")
+	}
+	if output.Len() != 0 {
+		t.Errorf("AppendArticle() wrote %q after failing", output.String())
+	}
+}
+
+func TestCheckSlugCollision(t *testing.T) {
+	existing := []OGMetadata{
+		{URL: "https://blog.example/blog/post/", Slug: "post"},
+		{URL: "https://blog.example/2023/post", Slug: "post-2"},
+	}
+
+	tests := []struct {
+		name         string
+		url          string
+		disambiguate bool
+		want         string
+	}{
+		{"same URL", "https://blog.example/blog/post/", true, "post"},
+		{"without the trailing slash", "https://blog.example/blog/post", true, "post"},
+		{"with a fragment and the default port", "https://blog.example:443/blog/post#top", true, "post"},
+		{"numbered variant imported again", "https://blog.example/2023/post/", true, "post-2"},
+		{"other path on the same host", "https://blog.example/2024/post", true, "post-3"},
+		{"other host", "https://www.other.example/post", true, "post-other-example"},
+		{"collision only reported", "https://blog.example/2024/post", false, "post"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata := OGMetadata{URL: tt.url, Slug: "post"}
+			checkSlugCollision(existing, &metadata, Options{DisambiguateSlugs: tt.disambiguate})
+			if metadata.Slug != tt.want {
+				t.Errorf("slug = %q, want %q", metadata.Slug, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddAlternateURL(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want []string
+	}{
+		{"new URL", "https://mirror.example/post", []string{"https://mirror.example/copy?a=1&b=2", "https://mirror.example/post"}},
+		{"article URL with a trailing slash", "https://blog.example/post/", []string{"https://mirror.example/copy?a=1&b=2"}},
+		{"alternate URL with the query reordered", "https://mirror.example/copy?b=2&a=1", []string{"https://mirror.example/copy?a=1&b=2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			articles := []OGMetadata{{
+				URL:           "https://blog.example/post",
+				Title:         "A Post",
+				Slug:          "post",
+				AlternateURLs: []string{"https://mirror.example/copy?a=1&b=2"},
+			}}
+			if !addAlternateURL(articles, OGMetadata{URL: tt.url, Title: "a  post"}, Options{}) {
+				t.Fatal("the article wasn't matched by its title")
+			}
+			if !slices.Equal(articles[0].AlternateURLs, tt.want) {
+				t.Errorf("alternate URLs = %v, want %v", articles[0].AlternateURLs, tt.want)
+			}
+		})
+	}
+}
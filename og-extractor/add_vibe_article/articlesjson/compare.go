@@ -0,0 +1,112 @@
+package articlesjson
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CanonicalizeURL normalizes a URL for comparison: the scheme and host are lowercased, default
+// ports, fragments and trailing slashes are removed, query parameters are sorted, and with
+// foldPathCase the path is lowercased. Strings that aren't absolute URLs are only trimmed.
+func CanonicalizeURL(rawURL string, foldPathCase bool) string {
+	rawURL = strings.TrimSpace(rawURL)
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if (u.Scheme == "http" && u.Port() == "80") || (u.Scheme == "https" && u.Port() == "443") {
+		u.Host = u.Hostname()
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.RawQuery != "" {
+		u.RawQuery = u.Query().Encode()
+	}
+
+	path := strings.TrimRight(u.EscapedPath(), "/")
+	if foldPathCase {
+		path = strings.ToLower(path)
+	}
+	u.RawPath = ""
+	u.Path, _ = url.PathUnescape(path)
+
+	return u.String()
+}
+
+// SameHost reports whether two URLs have the same host, ignoring case and a leading "www."
+func SameHost(a, b string) bool {
+	urlA, errA := url.Parse(a)
+	urlB, errB := url.Parse(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+
+	hostA := strings.TrimPrefix(strings.ToLower(urlA.Hostname()), "www.")
+	hostB := strings.TrimPrefix(strings.ToLower(urlB.Hostname()), "www.")
+
+	return hostA == hostB
+}
+
+// NormalizeTitle lowercases a title and collapses its whitespace for comparison
+func NormalizeTitle(title string) string {
+	return strings.Join(strings.Fields(strings.ToLower(title)), " ")
+}
+
+// MergeTags returns the union of the tags, ignoring case and keeping the first spelling seen
+func MergeTags(existing, added []string) []string {
+	var merged []string
+	seen := map[string]bool{}
+	for _, tag := range append(append([]string{}, existing...), added...) {
+		key := strings.ToLower(strings.TrimSpace(tag))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		merged = append(merged, tag)
+	}
+	return merged
+}
+
+// ParsePublishDate parses a publication date in any of the formats commonly found in metadata
+func ParsePublishDate(dateStr string) (time.Time, bool) {
+	return ParsePublishDateIn(dateStr, time.UTC)
+}
+
+// ParsePublishDateIn is like ParsePublishDate, but dates without a time zone are read in the given location
+func ParsePublishDateIn(dateStr string, location *time.Location) (time.Time, bool) {
+	layouts := []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05Z0700",
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+		time.RFC1123Z,
+		time.RFC1123,
+	}
+
+	dateStr = strings.TrimSpace(dateStr)
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, dateStr, location); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// SortByDate sorts articles from newest to oldest, placing articles without a parsable date last
+func SortByDate(articles []OGMetadata) {
+	sort.SliceStable(articles, func(i, j int) bool {
+		ti, okI := ParsePublishDate(articles[i].PublishDate)
+		tj, okJ := ParsePublishDate(articles[j].PublishDate)
+		if okI != okJ {
+			return okI
+		}
+		return ti.After(tj)
+	})
+}
@@ -0,0 +1,27 @@
+package articlesjson
+
+import "testing"
+
+func TestCanonicalizeURL(t *testing.T) {
+	tests := []struct {
+		url          string
+		foldPathCase bool
+		want         string
+	}{
+		{"https://Example.com:443/post/", false, "https://example.com/post"},
+		{"http://example.com:80/post#comments", false, "http://example.com/post"},
+		{"http://example.com:8080/post", false, "http://example.com:8080/post"},
+		{"https://example.com/post?b=2&a=1", false, "https://example.com/post?a=1&b=2"},
+		{"https://example.com/Blog/Post", false, "https://example.com/Blog/Post"},
+		{"https://example.com/Blog/Post", true, "https://example.com/blog/post"},
+		{" /relative/post ", false, "/relative/post"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := CanonicalizeURL(tt.url, tt.foldPathCase); got != tt.want {
+				t.Errorf("CanonicalizeURL(%q, %v) = %q, want %q", tt.url, tt.foldPathCase, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package articlesjson
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// noSourceGroup is the group of the articles without a source in a grouped file
+const noSourceGroup = "(no source)"
+
+// articlesLayout is the flat layout of the JSON file, {"articles": [...]}
+type articlesLayout struct {
+	Articles []OGMetadata `json:"articles"`
+}
+
+// sourcesLayout is the grouped layout of the JSON file, {"sources": {"Site": [...]}}
+type sourcesLayout struct {
+	Sources map[string][]OGMetadata `json:"sources"`
+}
+
+// MarshalJSON writes the collection in its layout, grouped by source or flat
+func (collection ArticlesCollection) MarshalJSON() ([]byte, error) {
+	if collection.Grouped {
+		return json.Marshal(sourcesLayout{Sources: groupBySource(collection.Articles)})
+	}
+	return json.Marshal(articlesLayout{Articles: collection.Articles})
+}
+
+// UnmarshalJSON reads a collection in either layout, remembering it so the file is written back
+// the same way. The articles of a grouped file are flattened in the order of the sources.
+func (collection *ArticlesCollection) UnmarshalJSON(data []byte) error {
+	var layout struct {
+		articlesLayout
+		sourcesLayout
+	}
+	err := json.Unmarshal(data, &layout)
+	if err != nil {
+		return err
+	}
+
+	collection.Articles = layout.Articles
+	collection.Grouped = layout.Articles == nil && layout.Sources != nil
+	if collection.Grouped {
+		collection.Articles = flattenSources(layout.Sources)
+	}
+	return nil
+}
+
+// groupBySource buckets the articles by source, keeping their order within each source
+func groupBySource(articles []OGMetadata) map[string][]OGMetadata {
+	groups := map[string][]OGMetadata{}
+	for _, article := range articles {
+		source := article.Source
+		if source == "" {
+			source = noSourceGroup
+		}
+		groups[source] = append(groups[source], article)
+	}
+	return groups
+}
+
+// flattenSources lists the articles of all sources, sorted by source name as they appear in the file
+func flattenSources(sources map[string][]OGMetadata) []OGMetadata {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	articles := []OGMetadata{}
+	for _, name := range names {
+		articles = append(articles, sources[name]...)
+	}
+	return articles
+}
@@ -0,0 +1,80 @@
+package articlesjson
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestGroupBySource(t *testing.T) {
+	articles := []OGMetadata{
+		{Slug: "first", Source: "Example Blog"},
+		{Slug: "second", Source: "Vibe Coding Weekly"},
+		{Slug: "third"},
+		{Slug: "fourth", Source: "Example Blog"},
+	}
+
+	groups := groupBySource(articles)
+	got := map[string][]string{}
+	for source, group := range groups {
+		for _, article := range group {
+			got[source] = append(got[source], article.Slug)
+		}
+	}
+	want := map[string][]string{
+		"Example Blog":       {"first", "fourth"},
+		"Vibe Coding Weekly": {"second"},
+		noSourceGroup:        {"third"},
+	}
+	if !maps.EqualFunc(got, want, slices.Equal[[]string]) {
+		t.Errorf("groups = %v, want %v", got, want)
+	}
+}
+
+func TestCollectionLayouts(t *testing.T) {
+	articles := []OGMetadata{
+		{Slug: "first", Source: "Example Blog"},
+		{Slug: "second", Source: "Vibe Coding Weekly"},
+		{Slug: "third", Source: "Example Blog"},
+	}
+
+	tests := []struct {
+		grouped   bool
+		wantKey   string
+		wantSlugs []string
+	}{
+		{false, "articles", []string{"first", "second", "third"}},
+		// Grouped files are read back in the order of the sources
+		{true, "sources", []string{"first", "third", "second"}},
+	}
+	for _, tt := range tests {
+		data, err := json.Marshal(ArticlesCollection{Articles: articles, Grouped: tt.grouped})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var keys map[string]json.RawMessage
+		if err := json.Unmarshal(data, &keys); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := keys[tt.wantKey]; !ok || len(keys) != 1 {
+			t.Errorf("grouped=%v: wrote %s, want only %q", tt.grouped, data, tt.wantKey)
+		}
+
+		var collection ArticlesCollection
+		if err := json.Unmarshal(data, &collection); err != nil {
+			t.Fatal(err)
+		}
+		if collection.Grouped != tt.grouped {
+			t.Errorf("grouped=%v: read back with grouped=%v", tt.grouped, collection.Grouped)
+		}
+		var slugs []string
+		for _, article := range collection.Articles {
+			slugs = append(slugs, article.Slug)
+		}
+		if !slices.Equal(slugs, tt.wantSlugs) {
+			t.Errorf("grouped=%v: slugs = %v, want %v", tt.grouped, slugs, tt.wantSlugs)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+)
+
+// auditedFields are the fields, by their JSON names, compared by -compare-with-live
+var auditedFields = []string{"title", "description", "image", "publishDate", "author", "source"}
+
+// fieldDrift is a field whose stored value differs from the one of the live page
+type fieldDrift struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// compareWithLive extracts every article of the JSON file again from its URL and prints, per
+// article, the fields whose live value differs from the stored one. With -apply, the stored
+// values are replaced by the live ones and the file is rewritten; a field the live page no longer
+// has is reported but kept, since that is more often an extraction failure than a removal.
+func compareWithLive(ctx context.Context, filePath string, opts Options) error {
+	collection, err := readCollection(filePath)
+	if err != nil {
+		return err
+	}
+
+	drifted, failed, applied := 0, 0, 0
+	for i, article := range collection.Articles {
+		if article.URL == "" {
+			warnf("article %q has no URL, skipping it", article.Slug)
+			continue
+		}
+
+		live, err := extractArticles(ctx, article.URL, opts)
+		if err == nil && len(live) != 1 {
+			err = fmt.Errorf("expected 1 article, got %d", len(live))
+		}
+		if err != nil {
+			warnf("%s: %v", article.URL, err)
+			failed++
+			continue
+		}
+
+		drifts := fieldDrifts(article, live[0])
+		if len(drifts) == 0 {
+			continue
+		}
+		drifted++
+
+		fmt.Printf("%s (%s):\n", article.Slug, article.URL)
+		for _, drift := range drifts {
+			fmt.Printf("  %s: %q -> %q\n", drift.Field, drift.Old, drift.New)
+			if opts.Apply && drift.New != "" {
+				setStringField(&collection.Articles[i], drift.Field, drift.New)
+				applied++
+			}
+		}
+	}
+
+	fmt.Printf("%d of %d article(s) drifted", drifted, len(collection.Articles))
+	if failed > 0 {
+		fmt.Printf(", %d could not be extracted", failed)
+	}
+	fmt.Println()
+
+	if applied == 0 {
+		return nil
+	}
+
+	// Create backup before rewriting the file
+	backupPath := createBackupPath(filePath)
+	err = createBackupFile(filePath, backupPath, os.FileMode(opts.OutputMode))
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	err = writeCollection(collection, filePath, os.FileMode(opts.OutputMode), opts.Indent.Indent())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated %d field(s) in %s\n", applied, filePath)
+	return nil
+}
+
+// fieldDrifts returns the audited fields whose values differ between the stored and live articles
+func fieldDrifts(stored, live OGMetadata) []fieldDrift {
+	storedValue, liveValue := reflect.ValueOf(stored), reflect.ValueOf(live)
+
+	var drifts []fieldDrift
+	for _, field := range auditedFields {
+		i, _ := metadataFieldIndex(field)
+		old, current := storedValue.Field(i).String(), liveValue.Field(i).String()
+		if old != current {
+			drifts = append(drifts, fieldDrift{Field: field, Old: old, New: current})
+		}
+	}
+	return drifts
+}
+
+// setStringField sets the string field of the article with the given JSON name
+func setStringField(metadata *OGMetadata, field, value string) {
+	i, _ := metadataFieldIndex(field)
+	reflect.ValueOf(metadata).Elem().Field(i).SetString(value)
+}
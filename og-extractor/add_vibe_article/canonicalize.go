@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// canonicalizeSlugs recomputes the slug of every article of the JSON file from its canonical
+// URL, or its URL, with the current slug rules, and rewrites the file if any slug changed
+func canonicalizeSlugs(filePath string, opts Options) error {
+	collection, err := readCollection(filePath)
+	if err != nil {
+		return err
+	}
+
+	changed := 0
+	for i, article := range collection.Articles {
+		url := article.URL
+		if isAbsoluteURL(article.CanonicalURL) {
+			url = article.CanonicalURL
+		}
+		if url == "" {
+			warnf("article %q has no URL, keeping its slug", article.Slug)
+			continue
+		}
+
+		slug, err := slugFor(url, opts)
+		if err != nil {
+			warnf("%s: %v, keeping slug %q", url, err, article.Slug)
+			continue
+		}
+
+		if slug != article.Slug {
+			fmt.Printf("%s -> %s\n", article.Slug, slug)
+			collection.Articles[i].Slug = slug
+			changed++
+		}
+	}
+
+	if changed == 0 {
+		fmt.Println("All slugs are up to date")
+		return nil
+	}
+
+	// Create backup before rewriting the file
+	backupPath := createBackupPath(filePath)
+	err = createBackupFile(filePath, backupPath, os.FileMode(opts.OutputMode))
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	err = writeCollection(collection, filePath, os.FileMode(opts.OutputMode), opts.Indent.Indent())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Updated %d slug(s) in %s\n", changed, filePath)
+	return nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalizeSlugs(t *testing.T) {
+	path := writeArticles(t, "articles.json",
+		OGMetadata{URL: "https://blog.example/posts/my-post/comments", Slug: "comments"},
+		OGMetadata{URL: "https://blog.example/p?id=1", CanonicalURL: "https://blog.example/posts/profiling/amp", Slug: "p"},
+		OGMetadata{URL: "https://blog.example/posts/current/amp", Slug: "current"},
+		OGMetadata{Slug: "no-url"},
+	)
+
+	result := runCLI(t, "-canonicalize-slugs", "-slug-segment", "-2", path)
+	if result.code != 0 {
+		t.Fatalf("exit code %d, stderr:\n%s", result.code, result.stderr)
+	}
+	if !strings.Contains(result.stdout, "Updated 2 slug(s)") {
+		t.Errorf("stdout = %q, want 2 slugs updated", result.stdout)
+	}
+
+	want := []string{"my-post", "profiling", "current", "no-url"}
+	if got := articleSlugs(readArticles(t, path)); !slices.Equal(got, want) {
+		t.Errorf("slugs = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"os"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// charsetPrescanBytes is how much of the page is searched for a <meta> charset declaration,
+// as in the WHATWG prescan
+const charsetPrescanBytes = 1024
+
+// decodePage converts an HTML page to UTF-8. A byte order mark takes precedence, then the charset
+// declared by the document itself, then the one of the Content-Type header. Servers often send a
+// default charset that doesn't match the page, so when both declare one and they disagree, the
+// document wins and the conflict is logged with -verbose. Without any declaration, the encoding
+// is guessed as UTF-8 or windows-1252.
+func decodePage(body []byte, contentType, url string, opts Options) []byte {
+	name := ""
+	if _, bomName, certain := charset.DetermineEncoding(body, ""); certain {
+		name = bomName
+	}
+
+	if name == "" {
+		var headerName string
+		if _, params, err := mime.ParseMediaType(contentType); err == nil && params["charset"] != "" {
+			if _, headerName = charset.Lookup(params["charset"]); headerName == "" && opts.Verbose {
+				fmt.Fprintf(os.Stderr, "%s: ignoring unknown charset %q of the Content-Type header\n", url, params["charset"])
+			}
+		}
+		metaName := metaCharset(body)
+
+		switch {
+		case metaName != "":
+			if headerName != "" && headerName != metaName && opts.Verbose {
+				fmt.Fprintf(os.Stderr, "%s: the Content-Type header declares %s but the document declares %s, using %s\n", url, headerName, metaName, metaName)
+			}
+			name = metaName
+		case headerName != "":
+			name = headerName
+		default:
+			_, name, _ = charset.DetermineEncoding(body, "")
+		}
+	}
+
+	encoding, _ := charset.Lookup(name)
+	if encoding == nil || name == "utf-8" {
+		return body
+	}
+	decoded, err := encoding.NewDecoder().Bytes(body)
+	if err != nil {
+		if opts.Verbose {
+			fmt.Fprintf(os.Stderr, "%s: failed to decode the page as %s: %v\n", url, name, err)
+		}
+		return body
+	}
+	return decoded
+}
+
+// metaCharset returns the canonical name of the charset declared by a <meta charset> or
+// <meta http-equiv="Content-Type"> element at the start of the page, or an empty string
+func metaCharset(body []byte) string {
+	if len(body) > charsetPrescanBytes {
+		body = body[:charsetPrescanBytes]
+	}
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if token.Data != "meta" {
+				continue
+			}
+
+			var declared, httpEquiv, content string
+			for _, attr := range token.Attr {
+				switch strings.ToLower(attr.Key) {
+				case "charset":
+					declared = attr.Val
+				case "http-equiv":
+					httpEquiv = attr.Val
+				case "content":
+					content = attr.Val
+				}
+			}
+			if declared == "" && strings.EqualFold(httpEquiv, "content-type") {
+				if _, params, err := mime.ParseMediaType(content); err == nil {
+					declared = params["charset"]
+				}
+			}
+			if declared == "" {
+				continue
+			}
+
+			// A page can't declare itself as UTF-16 once it was read as ASCII-compatible bytes
+			_, name := charset.Lookup(declared)
+			if strings.HasPrefix(name, "utf-16") {
+				name = "utf-8"
+			}
+			if name != "" {
+				return name
+			}
+		}
+	}
+}
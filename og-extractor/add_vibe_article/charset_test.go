@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPageCharset(t *testing.T) {
+	server := newFixtureServer(t)
+	const want = "Café Crème — Profiling naïve Go"
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		// The document knows its encoding better than the server
+		{"utf-8-meta-over-latin1-header", "text/html; charset=ISO-8859-1",
+			`<html><head><meta charset="utf-8"><meta property="og:title" content="Café Crème — Profiling naïve Go"></head></html>`},
+		{"latin1-meta-over-utf-8-header", "text/html; charset=utf-8",
+			"<html><head><meta http-equiv=\"Content-Type\" content=\"text/html; charset=iso-8859-1\"><meta property=\"og:title\" content=\"Caf\xe9 Cr\xe8me &#8212; Profiling na\xefve Go\"></head></html>"},
+		{"latin1-header-alone", "text/html; charset=latin1",
+			"<html><head><meta property=\"og:title\" content=\"Caf\xe9 Cr\xe8me &mdash; Profiling na\xefve Go\"></head></html>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{"Content-Type": {tt.contentType}}
+			url := server.handle("/blog/"+tt.name, fixture{Body: tt.body, Header: header})
+
+			metadata := extractPage(t, url, Options{})
+			if metadata.Title != want {
+				t.Errorf("title = %q, want %q", metadata.Title, want)
+			}
+		})
+	}
+}
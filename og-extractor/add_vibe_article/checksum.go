@@ -0,0 +1,68 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// checksumPath returns the path of the sidecar checksum file of the articles file
+func checksumPath(filePath string) string {
+	return filePath + ".sha256"
+}
+
+// fileChecksum returns the hex-encoded SHA-256 of the file content
+func fileChecksum(filePath string) (string, error) {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// writeChecksum records the checksum of the file in its sidecar file, in the format of
+// sha256sum so it can also be checked with `sha256sum -c`
+func writeChecksum(filePath string, mode os.FileMode) error {
+	sum, err := fileChecksum(filePath)
+	if err != nil {
+		return err
+	}
+
+	line := fmt.Sprintf("%s  %s\n", sum, filepath.Base(filePath))
+	return writeFileWithMode(checksumPath(filePath), []byte(line), mode)
+}
+
+// verifyChecksum checks the file against the checksum recorded by the previous run, returning
+// an error when it was modified since. Nothing is checked when either file doesn't exist yet.
+func verifyChecksum(filePath string) error {
+	recorded, err := ioutil.ReadFile(checksumPath(filePath))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read checksum file: %w", err)
+	}
+
+	fields := strings.Fields(string(recorded))
+	if len(fields) == 0 {
+		return fmt.Errorf("checksum file %s is empty", checksumPath(filePath))
+	}
+
+	sum, err := fileChecksum(filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !strings.EqualFold(sum, fields[0]) {
+		return fmt.Errorf("%s was modified outside of og-extractor since the last run (checksum mismatch)", filePath)
+	}
+	return nil
+}
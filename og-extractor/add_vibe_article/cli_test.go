@@ -0,0 +1,755 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cliEnv makes the test binary run the command instead of the tests, see runCLI
+const cliEnv = "OG_EXTRACTOR_RUN_CLI"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(cliEnv) != "" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// cliResult is the outcome of a run of the command
+type cliResult struct {
+	stdout string
+	stderr string
+	code   int
+}
+
+// runCLI runs the command with the arguments in a subprocess, the test binary itself, so
+// the behavior of main, such as its exit code and the handling of a batch of URLs, can be
+// tested end to end
+func runCLI(t *testing.T, args ...string) cliResult {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), cliEnv+"=1")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		t.Fatalf("running the command: %v", err)
+	}
+	return cliResult{stdout: stdout.String(), stderr: stderr.String(), code: cmd.ProcessState.ExitCode()}
+}
+
+func TestRunCLI(t *testing.T) {
+	server := newFixtureServer(t)
+	output := writeArticles(t, "articles.json")
+
+	result := runCLI(t, "-quiet", server.page("article.html"), output)
+	if result.code != 0 {
+		t.Fatalf("exit code %d, stderr:\n%s", result.code, result.stderr)
+	}
+	if got := articleSlugs(readArticles(t, output)); len(got) != 1 || got[0] != "article.html" {
+		t.Errorf("slugs = %v, want [article.html]", got)
+	}
+
+	if result := runCLI(t); result.code != 1 {
+		t.Errorf("exit code without arguments = %d, want 1", result.code)
+	}
+}
+
+func TestTotalTimeout(t *testing.T) {
+	server := newFixtureServer(t)
+	var urls []string
+	for _, path := range []string{"/slow-1", "/slow-2", "/slow-3"} {
+		urls = append(urls, server.handle(path, fixture{File: "article.html", Delay: 400 * time.Millisecond}))
+	}
+	output := writeArticles(t, "articles.json")
+
+	start := time.Now()
+	args := append([]string{"-quiet", "-fetch-timeout", "5s", "-total-timeout", "600ms"}, urls...)
+	result := runCLI(t, append(args, output)...)
+	elapsed := time.Since(start)
+
+	if result.code != 1 {
+		t.Errorf("exit code %d, want 1", result.code)
+	}
+	if elapsed > 2*time.Second {
+		t.Errorf("the run took %v, past the total timeout", elapsed)
+	}
+	if !strings.Contains(result.stderr, "Total timeout exceeded, skipping 1 remaining URL(s)") {
+		t.Errorf("stderr doesn't report the skipped URL:\n%s", result.stderr)
+	}
+	if got := len(readArticles(t, output)); got != 1 {
+		t.Errorf("imported %d articles, want the 1 fetched before the deadline", got)
+	}
+	if n := len(server.requestsTo("/slow-3")); n != 0 {
+		t.Errorf("the URL after the deadline was fetched %d time(s)", n)
+	}
+}
+
+func TestOutputPathIsDirectory(t *testing.T) {
+	server := newFixtureServer(t)
+	dir := t.TempDir()
+
+	result := runCLI(t, server.page("article.html"), dir)
+	if result.code != 1 {
+		t.Errorf("exit code %d, want 1", result.code)
+	}
+	if want := "Error: target path is a directory, expected a file: " + dir; !strings.Contains(result.stderr, want) {
+		t.Errorf("stderr = %q, want %q", result.stderr, want)
+	}
+	if n := len(server.requestsTo("/article.html")); n != 0 {
+		t.Errorf("the page was fetched %d time(s) before the path was checked", n)
+	}
+}
+
+func TestLimitPerHost(t *testing.T) {
+	server := newFixtureServer(t)
+	paths := []string{"/blog/one", "/blog/two", "/blog/three", "/blog/four"}
+	var urls []string
+	for _, path := range paths {
+		urls = append(urls, server.handle(path, fixture{File: "article.html"}))
+	}
+	// The same server under another host name
+	other := strings.Replace(server.handle("/blog/other", fixture{File: "article.html"}), "127.0.0.1", "localhost", 1)
+	output := writeArticles(t, "articles.json")
+
+	args := append([]string{"-quiet", "-max-per-host", "2"}, urls...)
+	result := runCLI(t, append(args, other, output)...)
+	if result.code != 0 {
+		t.Fatalf("exit code %d, stderr:\n%s", result.code, result.stderr)
+	}
+
+	for i, path := range paths {
+		want := 1
+		if i >= 2 {
+			want = 0
+		}
+		if n := len(server.requestsTo(path)); n != want {
+			t.Errorf("%s fetched %d time(s), want %d", path, n, want)
+		}
+	}
+	if got := articleSlugs(readArticles(t, output)); !slices.Equal(got, []string{"one", "two", "other"}) {
+		t.Errorf("imported slugs = %v, want [one two other]", got)
+	}
+}
+
+func TestExcludePattern(t *testing.T) {
+	server := newFixtureServer(t)
+	var urls []string
+	for _, path := range []string{"/blog/one", "/tag/go", "/blog/two", "/author/ada"} {
+		urls = append(urls, server.handle(path, fixture{File: "article.html"}))
+	}
+	output := writeArticles(t, "articles.json")
+
+	args := append([]string{"-quiet", "-exclude", "/tag/", "-exclude", "/author/"}, urls...)
+	result := runCLI(t, append(args, output)...)
+	if result.code != 0 {
+		t.Fatalf("exit code %d, stderr:\n%s", result.code, result.stderr)
+	}
+
+	for _, path := range []string{"/tag/go", "/author/ada"} {
+		if n := len(server.requestsTo(path)); n != 0 {
+			t.Errorf("excluded %s was fetched %d time(s)", path, n)
+		}
+	}
+	if got := articleSlugs(readArticles(t, output)); !slices.Equal(got, []string{"one", "two"}) {
+		t.Errorf("imported slugs = %v, want [one two]", got)
+	}
+}
+
+func TestModes(t *testing.T) {
+	server := newFixtureServer(t)
+	one := server.handle("/blog/one", fixture{File: "article.html"})
+	two := server.handle("/blog/two", fixture{File: "article.html"})
+
+	tests := []struct {
+		mode string
+		want []string
+	}{
+		{"append", []string{"old", "one", "two"}},
+		{"replace", []string{"one", "two"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			output := writeArticles(t, "articles.json", OGMetadata{URL: "https://blog.example/old", Slug: "old"})
+
+			result := runCLI(t, "-quiet", "-mode", tt.mode, one, two, output)
+			if result.code != 0 {
+				t.Fatalf("exit code %d, stderr:\n%s", result.code, result.stderr)
+			}
+			if got := articleSlugs(readArticles(t, output)); !slices.Equal(got, tt.want) {
+				t.Errorf("slugs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailuresFile(t *testing.T) {
+	server := newFixtureServer(t)
+	good := server.handle("/blog/good", fixture{File: "article.html"})
+	missing := server.page("missing.html")
+	output := writeArticles(t, "articles.json")
+	failuresPath := filepath.Join(filepath.Dir(output), "failures.json")
+
+	start := time.Now().UTC().Truncate(time.Second)
+	result := runCLI(t, "-quiet", "-record-failures", failuresPath, missing, good, output)
+	if result.code != 1 {
+		t.Errorf("exit code %d, want 1", result.code)
+	}
+
+	data, err := os.ReadFile(failuresPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failures FailuresCollection
+	if err := json.Unmarshal(data, &failures); err != nil {
+		t.Fatal(err)
+	}
+	if len(failures.Failures) != 1 {
+		t.Fatalf("got %d failure records, want 1: %s", len(failures.Failures), data)
+	}
+
+	record := failures.Failures[0]
+	if record.URL != missing {
+		t.Errorf("url = %q, want %q", record.URL, missing)
+	}
+	if !strings.Contains(record.Error, "status code 404") {
+		t.Errorf("error = %q, want the 404", record.Error)
+	}
+	if failedAt, err := time.Parse(time.RFC3339, record.FailedAt); err != nil || failedAt.Before(start) {
+		t.Errorf("failedAt = %q, want an RFC 3339 time of the run", record.FailedAt)
+	}
+}
+
+func TestManifestOverrides(t *testing.T) {
+	server := newFixtureServer(t)
+	first := server.handle("/blog/first", fixture{File: "article.html"})
+	second := server.handle("/blog/second", fixture{File: "article.html"})
+	third := server.handle("/blog/third", fixture{File: "article.html"})
+
+	dir := t.TempDir()
+	manifestPath := filepath.Join(dir, "manifest.yaml")
+	manifest := "output: articles.json\n" +
+		"defaults:\n" +
+		"  source: Vibe Coding Weekly\n" +
+		"  tags: [weekly]\n" +
+		"urls:\n" +
+		"  - " + first + "\n" +
+		"  - url: " + second + "\n" +
+		"    publishDate: \"2023-05-15\"\n" +
+		"    tags: [go, tooling]\n" +
+		"  - url: " + third + "\n" +
+		"    source: Guest Post\n"
+	if err := os.WriteFile(manifestPath, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := runCLI(t, "-quiet", "-manifest", manifestPath)
+	if result.code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", result.code, result.stderr)
+	}
+
+	articles := readArticles(t, filepath.Join(dir, "articles.json"))
+	bySlug := map[string]OGMetadata{}
+	for _, article := range articles {
+		bySlug[article.Slug] = article
+	}
+
+	tests := []struct {
+		slug        string
+		publishDate string
+		tags        []string
+		source      string
+	}{
+		{"first", "2024-03-15T09:30:00Z", []string{"weekly"}, "Vibe Coding Weekly"},
+		{"second", "2023-05-15", []string{"go", "tooling"}, "Vibe Coding Weekly"},
+		{"third", "2024-03-15T09:30:00Z", []string{"weekly"}, "Guest Post"},
+	}
+	for _, test := range tests {
+		t.Run(test.slug, func(t *testing.T) {
+			article, ok := bySlug[test.slug]
+			if !ok {
+				t.Fatalf("no article with slug %q in %v", test.slug, articleSlugs(articles))
+			}
+			if article.PublishDate != test.publishDate {
+				t.Errorf("publishDate = %q, want %q", article.PublishDate, test.publishDate)
+			}
+			if !slices.Equal(article.Tags, test.tags) {
+				t.Errorf("tags = %v, want %v", article.Tags, test.tags)
+			}
+			if article.Source != test.source {
+				t.Errorf("source = %q, want %q", article.Source, test.source)
+			}
+		})
+	}
+}
+
+func TestFailFast(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		wantAfter int
+		wantSlugs []string
+	}{
+		{"continue by default", nil, 1, []string{"before", "after"}},
+		{"fail fast", []string{"-fail-fast"}, 0, []string{"before"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newFixtureServer(t)
+			before := server.handle("/blog/before", fixture{File: "article.html"})
+			missing := server.page("missing.html")
+			after := server.handle("/blog/after", fixture{File: "article.html"})
+			output := writeArticles(t, "articles.json")
+
+			args := append([]string{"-quiet"}, test.args...)
+			result := runCLI(t, append(args, before, missing, after, output)...)
+			if result.code != 1 {
+				t.Errorf("exit code %d, want 1", result.code)
+			}
+
+			if got := len(server.requestsTo("/blog/after")); got != test.wantAfter {
+				t.Errorf("the URL after the failure was requested %d times, want %d", got, test.wantAfter)
+			}
+			if got := articleSlugs(readArticles(t, output)); !slices.Equal(got, test.wantSlugs) {
+				t.Errorf("slugs = %v, want %v", got, test.wantSlugs)
+			}
+		})
+	}
+}
+
+func TestChecksumFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		flag      string
+		tamper    bool
+		wantCode  int
+		wantWarn  bool
+		wantSlugs []string
+	}{
+		{"untouched", "-verify-checksum", false, 0, false, []string{"first", "second"}},
+		{"tampered with -checksum-file", "-checksum-file", true, 0, true, []string{"first", "second"}},
+		{"tampered with -verify-checksum", "-verify-checksum", true, 1, true, []string{"first"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newFixtureServer(t)
+			first := server.handle("/blog/first", fixture{File: "article.html"})
+			second := server.handle("/blog/second", fixture{File: "article.html"})
+			output := writeArticles(t, "articles.json")
+
+			result := runCLI(t, "-quiet", "-checksum-file", first, output)
+			if result.code != 0 {
+				t.Fatalf("first run: exit code %d, stderr: %s", result.code, result.stderr)
+			}
+			if _, err := os.Stat(output + ".sha256"); err != nil {
+				t.Fatalf("no checksum file after the first run: %v", err)
+			}
+
+			if test.tamper {
+				data, err := os.ReadFile(output)
+				if err != nil {
+					t.Fatal(err)
+				}
+				edited := strings.Replace(string(data), "Building a Blog", "Building a Site", 1)
+				if err := os.WriteFile(output, []byte(edited), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result = runCLI(t, "-quiet", test.flag, second, output)
+			if result.code != test.wantCode {
+				t.Errorf("exit code %d, want %d, stderr: %s", result.code, test.wantCode, result.stderr)
+			}
+			if warned := strings.Contains(result.stderr, "checksum mismatch"); warned != test.wantWarn {
+				t.Errorf("stderr = %q, want a checksum mismatch reported: %v", result.stderr, test.wantWarn)
+			}
+			if got := articleSlugs(readArticles(t, output)); !slices.Equal(got, test.wantSlugs) {
+				t.Errorf("slugs = %v, want %v", got, test.wantSlugs)
+			}
+		})
+	}
+}
+
+func TestImportLog(t *testing.T) {
+	server := newFixtureServer(t)
+	good := server.handle("/blog/good", fixture{File: "article.html"})
+	missing := server.page("missing.html")
+	output := writeArticles(t, "articles.json")
+	logPath := filepath.Join(filepath.Dir(output), "import.ndjson")
+
+	start := time.Now().UTC().Truncate(time.Second)
+	result := runCLI(t, "-quiet", "-log-file", logPath, good, missing, output)
+	if result.code != 1 {
+		t.Errorf("first run: exit code %d, want 1", result.code)
+	}
+	// A second run appends to the log of the first
+	result = runCLI(t, "-quiet", "-log-file", logPath, "-exclude", "/blog/", good, output)
+	if result.code != 0 {
+		t.Errorf("second run: exit code %d, stderr: %s", result.code, result.stderr)
+	}
+
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+
+	tests := []struct {
+		url           string
+		result        string
+		articles      int
+		fieldsMissing []string
+		reason        string
+	}{
+		{good, importImported, 1, []string{"url"}, ""},
+		{missing, importFailed, 0, nil, "status code 404"},
+		{good, importSkipped, 0, nil, "-exclude"},
+	}
+	if len(lines) != len(tests) {
+		t.Fatalf("got %d log lines, want %d:\n%s", len(lines), len(tests), data)
+	}
+	for i, tt := range tests {
+		var record ImportLogRecord
+		if err := json.Unmarshal([]byte(lines[i]), &record); err != nil {
+			t.Fatalf("line %d: %v", i+1, err)
+		}
+		if record.URL != tt.url || record.Result != tt.result || record.Articles != tt.articles {
+			t.Errorf("line %d: url=%q result=%q articles=%d, want %q %q %d", i+1, record.URL, record.Result, record.Articles, tt.url, tt.result, tt.articles)
+		}
+		if !slices.Equal(record.FieldsMissing, tt.fieldsMissing) {
+			t.Errorf("line %d: fieldsMissing = %v, want %v", i+1, record.FieldsMissing, tt.fieldsMissing)
+		}
+		if !strings.Contains(record.Reason, tt.reason) || (tt.reason == "") != (record.Reason == "") {
+			t.Errorf("line %d: reason = %q, want it to contain %q", i+1, record.Reason, tt.reason)
+		}
+		if at, err := time.Parse(time.RFC3339, record.Timestamp); err != nil || at.Before(start) {
+			t.Errorf("line %d: timestamp = %q, want an RFC 3339 time of the run", i+1, record.Timestamp)
+		}
+	}
+}
+
+func TestOutputByCategory(t *testing.T) {
+	server := newFixtureServer(t)
+	sectionPage := func(section string) fixture {
+		return fixture{Body: `<html><head><meta property="og:title" content="Post">` +
+			`<meta property="article:section" content="` + section + `"></head></html>`}
+	}
+	tech := server.handle("/blog/go-profiling", sectionPage("Tech"))
+	devOps := server.handle("/blog/on-call", sectionPage("Dev / Ops"))
+	uncategorized := server.handle("/blog/vibe-coding", fixture{File: "article.html"})
+
+	outputDir := t.TempDir()
+	result := runCLI(t, "-quiet", "-format", "frontmatter", "-output-by-category", tech, devOps, uncategorized, outputDir)
+	if result.code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", result.code, result.stderr)
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{tech, "tech/go-profiling.md"},
+		{devOps, "dev-ops/on-call.md"},
+		{uncategorized, "vibe-coding.md"},
+	}
+	for _, tt := range tests {
+		if _, err := os.Stat(filepath.Join(outputDir, filepath.FromSlash(tt.want))); err != nil {
+			t.Errorf("%s: %v", tt.url, err)
+		}
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	tests := []struct {
+		name         string
+		budget       string
+		wantRequests []int
+		wantWarnings int
+	}{
+		{"spent after two retries", "2", []int{2, 2, 1, 1}, 1},
+		{"no limit", "0", []int{2, 2, 2, 2}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newFixtureServer(t)
+			args := []string{"-quiet", "-retry-different-ua", "-retry-budget", test.budget}
+			paths := []string{"/blog/first", "/blog/second", "/blog/third", "/blog/fourth"}
+			for _, path := range paths {
+				args = append(args, server.handle(path, fixture{Status: http.StatusForbidden}))
+			}
+
+			result := runCLI(t, append(args, writeArticles(t, "articles.json"))...)
+			if result.code != 1 {
+				t.Errorf("exit code %d, want 1", result.code)
+			}
+
+			var requests []int
+			for _, path := range paths {
+				requests = append(requests, len(server.requestsTo(path)))
+			}
+			if !slices.Equal(requests, test.wantRequests) {
+				t.Errorf("requests per URL = %v, want %v", requests, test.wantRequests)
+			}
+			if warnings := strings.Count(result.stderr, "retry budget of"); warnings != test.wantWarnings {
+				t.Errorf("got %d budget warnings, want %d, stderr: %s", warnings, test.wantWarnings, result.stderr)
+			}
+		})
+	}
+}
+
+func TestPartialResultAtDeadline(t *testing.T) {
+	server := newFixtureServer(t)
+	// The head is in the first half of the body, sent before the delay
+	page := `<html><head>
+<meta property="og:title" content="Building a Blog with Vibe Coding">
+<meta property="og:description" content="How we built our blog by describing it to an assistant.">
+</head><body><article>` + strings.Repeat("<p>It started with a prompt.</p>\n", 200) + `</article></body></html>`
+	url := server.handle("/blog/slow-body", fixture{Body: page, BodyDelay: 5 * time.Second})
+	output := writeArticles(t, "articles.json")
+
+	start := time.Now()
+	result := runCLI(t, "-quiet", "-fetch-timeout", "10s", "-total-timeout", "500ms", url, output)
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("the run took %v, past the total timeout", elapsed)
+	}
+	if result.code != 0 {
+		t.Errorf("exit code %d, stderr: %s", result.code, result.stderr)
+	}
+	if !strings.Contains(result.stderr, "deadline exceeded during extraction, partial result") {
+		t.Errorf("stderr doesn't warn about the partial result:\n%s", result.stderr)
+	}
+
+	articles := readArticles(t, output)
+	if len(articles) != 1 {
+		t.Fatalf("imported %d articles, want the partial one", len(articles))
+	}
+	if articles[0].Title != "Building a Blog with Vibe Coding" || articles[0].Description == "" {
+		t.Errorf("article = %+v, want the fields of the head", articles[0])
+	}
+}
+
+func TestLeftoverTempFile(t *testing.T) {
+	recovered := OGMetadata{URL: "https://blog.example/recovered", Slug: "recovered"}
+	existing := OGMetadata{URL: "https://blog.example/existing", Slug: "existing"}
+
+	tests := []struct {
+		name        string
+		existing    bool
+		temp        string
+		wantWarning string
+		wantSlugs   []string
+	}{
+		{"file intact", true, `{"articles": [`, "is intact", []string{"existing", "new"}},
+		{"complete temp file", false, "", "recovering", []string{"recovered", "new"}},
+		{"truncated temp file", false, `{"articles": [{"url": "https://blog.e`, "an incomplete file", []string{"new"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newFixtureServer(t)
+			url := server.handle("/blog/new", fixture{File: "article.html"})
+
+			var output string
+			if test.existing {
+				output = writeArticles(t, "articles.json", existing)
+			} else {
+				output = writeArticles(t, "articles.json")
+			}
+			// A complete temp file holds the collection that was about to replace the file
+			temp := []byte(test.temp)
+			if test.temp == "" {
+				var err error
+				if temp, err = marshalCollection(ArticlesCollection{Articles: []OGMetadata{recovered}}, "  "); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := os.WriteFile(output+tempFileSuffix, temp, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			result := runCLI(t, "-quiet", url, output)
+			if result.code != 0 {
+				t.Fatalf("exit code %d, stderr: %s", result.code, result.stderr)
+			}
+			if !strings.Contains(result.stderr, test.wantWarning) {
+				t.Errorf("stderr = %q, want a warning containing %q", result.stderr, test.wantWarning)
+			}
+			if _, err := os.Stat(output + tempFileSuffix); !os.IsNotExist(err) {
+				t.Errorf("the temp file is left behind: %v", err)
+			}
+			if got := articleSlugs(readArticles(t, output)); !slices.Equal(got, test.wantSlugs) {
+				t.Errorf("slugs = %v, want %v", got, test.wantSlugs)
+			}
+		})
+	}
+}
+
+func TestLeftoverTempFileOfMaintenancePass(t *testing.T) {
+	recovered := OGMetadata{URL: "https://blog.example/recovered", Slug: "recovered"}
+	output := writeArticles(t, "articles.json")
+	temp, err := marshalCollection(ArticlesCollection{Articles: []OGMetadata{recovered}}, "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(output+tempFileSuffix, temp, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result := runCLI(t, "-quiet", "-sort", output)
+	if result.code != 0 {
+		t.Fatalf("exit code %d, stderr: %s", result.code, result.stderr)
+	}
+	if !strings.Contains(result.stderr, "recovering") {
+		t.Errorf("stderr = %q, want a warning about the recovery", result.stderr)
+	}
+	if got := articleSlugs(readArticles(t, output)); !slices.Equal(got, []string{"recovered"}) {
+		t.Errorf("slugs = %v, want [recovered]", got)
+	}
+}
+
+func TestCompareWithLive(t *testing.T) {
+	tests := []struct {
+		name      string
+		apply     bool
+		wantTitle string
+	}{
+		{"report only", false, "Building a Blog with Vibes"},
+		{"apply", true, "Building a Blog with Vibe Coding"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := newFixtureServer(t)
+			changed := server.handle("/blog/changed", fixture{File: "article.html"})
+			unchanged := server.handle("/blog/unchanged", fixture{File: "article.html"})
+
+			// The stored articles are the live ones, with the title the first had when imported
+			stored := extractPage(t, changed, Options{})
+			stored.URL = changed
+			stored.Title = "Building a Blog with Vibes"
+			same := extractPage(t, unchanged, Options{})
+			same.URL = unchanged
+			output := writeArticles(t, "articles.json", stored, same)
+
+			args := []string{"-compare-with-live", output}
+			if test.apply {
+				args = append([]string{"-apply"}, args...)
+			}
+			result := runCLI(t, args...)
+			if result.code != 0 {
+				t.Fatalf("exit code %d, stderr: %s", result.code, result.stderr)
+			}
+
+			want := "changed (" + changed + "):\n" +
+				`  title: "Building a Blog with Vibes" -> "Building a Blog with Vibe Coding"` + "\n" +
+				"1 of 2 article(s) drifted\n"
+			if !strings.Contains(result.stdout, want) {
+				t.Errorf("stdout = %q, want the drift reported as %q", result.stdout, want)
+			}
+			if strings.Contains(result.stdout, "unchanged (") {
+				t.Errorf("the unchanged article is reported:\n%s", result.stdout)
+			}
+
+			articles := readArticles(t, output)
+			if articles[0].Title != test.wantTitle {
+				t.Errorf("stored title = %q, want %q", articles[0].Title, test.wantTitle)
+			}
+		})
+	}
+}
+
+func TestTrustFetchedURL(t *testing.T) {
+	server := newFixtureServer(t)
+	url := server.handle("/posts/syndicated", fixture{Body: `<html><head>
+<meta property="og:title" content="Profiling Go Services">
+<meta property="og:url" content="https://other.example/posts/syndicated">
+</head></html>`})
+
+	tests := []struct {
+		trust   bool
+		wantURL string
+	}{
+		{false, "https://other.example/posts/syndicated"},
+		{true, url},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint("trust=", tt.trust), func(t *testing.T) {
+			output := writeArticles(t, "articles.json")
+			result := runCLI(t, "-quiet", fmt.Sprint("-trust-fetched-url=", tt.trust), url, output)
+			if result.code != 0 {
+				t.Fatalf("exit code %d, stderr: %s", result.code, result.stderr)
+			}
+			if !strings.Contains(result.stderr, "points to a different host than the fetched URL") {
+				t.Errorf("stderr = %q, want a warning about the og:url host", result.stderr)
+			}
+			if articles := readArticles(t, output); len(articles) != 1 || articles[0].URL != tt.wantURL {
+				t.Errorf("articles = %+v, want the url %q", articles, tt.wantURL)
+			}
+		})
+	}
+}
+
+func TestJavaScriptShellWarning(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		page        string
+		wantWarning bool
+	}{
+		{"spa-shell.html", true},
+		// No OG tags either, but no mount point
+		{"title-only.html", false},
+		{"article.html", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			output := writeArticles(t, "articles.json")
+			result := runCLI(t, "-quiet", server.page(tt.page), output)
+			if result.code != 0 {
+				t.Fatalf("exit code %d, stderr: %s", result.code, result.stderr)
+			}
+			if warned := strings.Contains(result.stderr, "page appears to require JavaScript"); warned != tt.wantWarning {
+				t.Errorf("stderr = %q, want the JavaScript warning: %v", result.stderr, tt.wantWarning)
+			}
+		})
+	}
+}
+
+func TestSkipPaywalled(t *testing.T) {
+	server := newFixtureServer(t)
+	paywalled := server.handle("/posts/state-of-go-tooling", fixture{File: "paywalled.html"})
+	free := server.handle("/posts/vibe-coding", fixture{File: "article.html"})
+
+	tests := []struct {
+		skip      bool
+		wantSlugs []string
+	}{
+		{false, []string{"state-of-go-tooling", "vibe-coding"}},
+		{true, []string{"vibe-coding"}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint("skip=", tt.skip), func(t *testing.T) {
+			output := writeArticles(t, "articles.json")
+			result := runCLI(t, "-quiet", fmt.Sprint("-skip-paywalled=", tt.skip), paywalled, free, output)
+			if result.code != 0 {
+				t.Fatalf("exit code %d, stderr: %s", result.code, result.stderr)
+			}
+			if got := articleSlugs(readArticles(t, output)); !slices.Equal(got, tt.wantSlugs) {
+				t.Errorf("slugs = %v, want %v", got, tt.wantSlugs)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/atotto/clipboard"
+)
+
+// readClipboard returns the text content of the system clipboard
+var readClipboard = clipboard.ReadAll
+
+// urlFromClipboard reads a single http(s) URL from the system clipboard
+func urlFromClipboard() (string, error) {
+	text, err := readClipboard()
+	if err != nil {
+		return "", fmt.Errorf("failed to read the clipboard: %w", err)
+	}
+
+	url := strings.TrimSpace(text)
+	if !isAbsoluteURL(url) {
+		if len(url) > 80 {
+			url = url[:80] + "..."
+		}
+		return "", fmt.Errorf("the clipboard does not contain a URL: %q", url)
+	}
+
+	return url, nil
+}
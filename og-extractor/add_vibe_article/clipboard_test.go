@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestURLFromClipboard(t *testing.T) {
+	tests := []struct {
+		name      string
+		clipboard string
+		err       error
+		want      string
+		wantErr   bool
+	}{
+		{"url", "https://blog.example/posts/profiling", nil, "https://blog.example/posts/profiling", false},
+		{"surrounding whitespace", "  https://blog.example/posts/profiling\n", nil, "https://blog.example/posts/profiling", false},
+		{"not a url", "some copied text", nil, "", true},
+		{"empty", "", nil, "", true},
+		{"unavailable", "", errors.New("no clipboard utility"), "", true},
+	}
+
+	original := readClipboard
+	t.Cleanup(func() { readClipboard = original })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readClipboard = func() (string, error) { return tt.clipboard, tt.err }
+			url, err := urlFromClipboard()
+			if (err != nil) != tt.wantErr || url != tt.want {
+				t.Errorf("urlFromClipboard() = %q, %v, want %q (error %v)", url, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import "strings"
+
+// Sources of the core fields recorded by -include-confidence
+const (
+	sourceOG           = "og"
+	sourceTwitter      = "twitter"
+	sourceJSONLD       = "jsonld"
+	sourceFramework    = "framework-data"
+	sourceHTMLFallback = "html-fallback"
+	sourceTitle        = "title"
+	sourceURLGuess     = "url-guess"
+)
+
+// sourceScores is how much a field obtained from each source can be trusted, from 0 to 1.
+// Structured metadata the site declares for the article scores highest, values inferred from
+// the page content lower, and guesses from the URL lowest.
+var sourceScores = map[string]float64{
+	sourceOG:           0.9,
+	sourceJSONLD:       0.9,
+	sourceTwitter:      0.8,
+	sourceFramework:    0.7,
+	sourceHTMLFallback: 0.5,
+	sourceTitle:        0.4,
+	sourceURLGuess:     0.3,
+}
+
+// confidenceTracker records the source of the core fields as the extraction steps fill them,
+// keyed by the names of the missing-field report. A nil tracker records nothing.
+// The URL isn't scored, since the fetched URL is known either way.
+type confidenceTracker map[string]FieldConfidence
+
+// newConfidenceTracker returns a tracker when -include-confidence is given, and nil otherwise
+func newConfidenceTracker(opts Options) confidenceTracker {
+	if !opts.IncludeConfidence {
+		return nil
+	}
+	return confidenceTracker{}
+}
+
+// track runs an extraction step and attributes the core fields it changed to the source
+func (tracker confidenceTracker) track(metadata *OGMetadata, source string, step func()) {
+	before := tracker.snapshot(*metadata)
+	step()
+	tracker.attribute(metadata, before, source)
+}
+
+// snapshot returns the values of the core fields, to find out which ones a step changes
+func (tracker confidenceTracker) snapshot(metadata OGMetadata) []string {
+	if tracker == nil {
+		return nil
+	}
+	values := make([]string, len(reportedFields))
+	for i, field := range reportedFields {
+		values[i] = field.Value(metadata)
+	}
+	return values
+}
+
+// attribute records the source of the core fields that changed since the snapshot, and forgets
+// the source of the ones that were cleared
+func (tracker confidenceTracker) attribute(metadata *OGMetadata, before []string, source string) {
+	if tracker == nil {
+		return
+	}
+	for i, field := range reportedFields {
+		if !isConfidenceField(field.Name) {
+			continue
+		}
+		if value := field.Value(*metadata); value != before[i] {
+			if value == "" {
+				delete(tracker, field.Name)
+			} else {
+				tracker[field.Name] = FieldConfidence{Source: source, Score: sourceScores[source]}
+			}
+		}
+	}
+}
+
+// apply stores the sources in the article, leaving out the fields that ended up empty
+func (tracker confidenceTracker) apply(metadata *OGMetadata) {
+	if tracker == nil {
+		return
+	}
+
+	confidence := map[string]FieldConfidence{}
+	for _, field := range reportedFields {
+		if entry, ok := tracker[field.Name]; ok && field.Value(*metadata) != "" {
+			confidence[field.Name] = entry
+		}
+	}
+	if len(confidence) > 0 {
+		metadata.Confidence = confidence
+	}
+}
+
+// isConfidenceField reports whether -include-confidence records the source of the field
+func isConfidenceField(name string) bool {
+	return name != "url"
+}
+
+// metaPropertySource returns the source of a meta tag: Open Graph and its type namespaces,
+// the Twitter card, or a plain HTML meta tag
+func metaPropertySource(property string) string {
+	prefix, _, _ := strings.Cut(property, ":")
+	switch prefix {
+	case "og", "article", "video", "profile", "book":
+		return sourceOG
+	case "twitter":
+		return sourceTwitter
+	}
+	return sourceHTMLFallback
+}
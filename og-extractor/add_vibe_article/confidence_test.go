@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestTitleConfidence(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		page       string
+		wantTitle  string
+		wantSource string
+	}{
+		{"article.html", "Building a Blog with Vibe Coding", sourceOG},
+		{"title-only.html", "Notes on Go Generics | Example Blog", sourceTitle},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			metadata := extractPage(t, server.page(tt.page), Options{IncludeConfidence: true})
+			if metadata.Title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", metadata.Title, tt.wantTitle)
+			}
+			if got := metadata.Confidence["title"]; got.Source != tt.wantSource || got.Score != sourceScores[tt.wantSource] {
+				t.Errorf("title confidence = %+v, want source %q", got, tt.wantSource)
+			}
+		})
+	}
+
+	if sourceScores[sourceTitle] >= sourceScores[sourceOG] {
+		t.Errorf("<title> scores %v, not lower than og:title at %v", sourceScores[sourceTitle], sourceScores[sourceOG])
+	}
+}
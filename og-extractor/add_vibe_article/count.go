@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// countArticles counts the articles of a JSON file by streaming it, without holding the
+// articles in memory, so very large files can be counted quickly
+func countArticles(filePath string) (int, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+
+	token, err := decoder.Token()
+	if err != nil {
+		return 0, fmt.Errorf("invalid JSON format: %w", err)
+	}
+	if token != json.Delim('{') {
+		return 0, fmt.Errorf("invalid JSON format: expected an object")
+	}
+
+	count := 0
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return 0, fmt.Errorf("invalid JSON format: %w", err)
+		}
+
+		switch key {
+		case "articles":
+			err = countArray(decoder, "articles", &count)
+		case "sources":
+			// A file grouped by source has an array of articles per source
+			err = countSources(decoder, &count)
+		default:
+			// Skip the values of other keys
+			var skipped json.RawMessage
+			err = decoder.Decode(&skipped)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("invalid JSON format: %w", err)
+		}
+	}
+
+	return count, nil
+}
+
+// countArray adds the number of elements of the array at the decoder position to the count,
+// accepting null as an empty array
+func countArray(decoder *json.Decoder, name string, count *int) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if token != json.Delim('[') {
+		return fmt.Errorf("%s is not an array", name)
+	}
+
+	for decoder.More() {
+		var article json.RawMessage
+		if err := decoder.Decode(&article); err != nil {
+			return fmt.Errorf("in article %d: %w", *count+1, err)
+		}
+		*count++
+	}
+
+	// Consume the closing bracket
+	_, err = decoder.Token()
+	return err
+}
+
+// countSources adds the number of articles of each source of a grouped file to the count
+func countSources(decoder *json.Decoder, count *int) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if token != json.Delim('{') {
+		return fmt.Errorf("sources is not an object")
+	}
+
+	for decoder.More() {
+		source, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if err := countArray(decoder, fmt.Sprintf("source %q", source), count); err != nil {
+			return err
+		}
+	}
+
+	// Consume the closing brace
+	_, err = decoder.Token()
+	return err
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCountArticles(t *testing.T) {
+	var articles []OGMetadata
+	for i := range 20000 {
+		articles = append(articles, OGMetadata{
+			URL:    fmt.Sprintf("https://blog.example/posts/%d", i),
+			Title:  fmt.Sprintf(`Post "%d" [draft]`, i),
+			Slug:   fmt.Sprint(i),
+			Source: fmt.Sprintf("Site %d", i%7),
+			Tags:   []string{"go", "{braces}"},
+		})
+	}
+
+	for _, grouped := range []bool{false, true} {
+		path := writeArticles(t, "articles.json")
+		err := writeCollection(ArticlesCollection{Articles: articles, Grouped: grouped}, path, 0, "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		count, err := countArticles(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed := len(readArticles(t, path)); count != parsed || count != len(articles) {
+			t.Errorf("grouped %v: streamed count %d, full parse %d, want %d", grouped, count, parsed, len(articles))
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"add_vibe_article/articlesjson"
+)
+
+// similarTitles is a pair of articles whose titles are likely to be the same article
+type similarTitles struct {
+	First      OGMetadata
+	Second     OGMetadata
+	Similarity float64
+}
+
+// findSimilarTitles compares the titles of all articles pairwise and returns the pairs
+// whose similarity is at least the threshold. Articles without a title are ignored.
+func findSimilarTitles(articles []OGMetadata, threshold float64) []similarTitles {
+	var pairs []similarTitles
+
+	titles := make([]string, len(articles))
+	for i, article := range articles {
+		titles[i] = articlesjson.NormalizeTitle(article.Title)
+	}
+
+	for i := 0; i < len(articles); i++ {
+		if titles[i] == "" {
+			continue
+		}
+		for j := i + 1; j < len(articles); j++ {
+			if titles[j] == "" {
+				continue
+			}
+			similarity := titleSimilarity(titles[i], titles[j])
+			if similarity >= threshold {
+				pairs = append(pairs, similarTitles{
+					First:      articles[i],
+					Second:     articles[j],
+					Similarity: similarity,
+				})
+			}
+		}
+	}
+
+	return pairs
+}
+
+// titleSimilarity returns the Levenshtein ratio of two strings, from 0 (different) to 1 (identical)
+func titleSimilarity(a, b string) float64 {
+	runesA, runesB := []rune(a), []rune(b)
+	longest := len(runesA)
+	if len(runesB) > longest {
+		longest = len(runesB)
+	}
+	if longest == 0 {
+		return 1
+	}
+
+	return 1 - float64(levenshtein(runesA, runesB))/float64(longest)
+}
+
+// levenshtein computes the edit distance between two rune slices
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+// auditDuplicateTitles reports the likely duplicate articles in a JSON file without modifying it
+func auditDuplicateTitles(filePath string, threshold float64) error {
+	collection, err := readCollection(filePath)
+	if err != nil {
+		return err
+	}
+
+	printSimilarTitles(findSimilarTitles(collection.Articles, threshold))
+	return nil
+}
+
+// printSimilarTitles reports the likely duplicates found in the collection
+func printSimilarTitles(pairs []similarTitles) {
+	if len(pairs) == 0 {
+		fmt.Println("\nNo likely duplicate titles found")
+		return
+	}
+
+	fmt.Printf("\nFound %d likely duplicate title pair(s):\n", len(pairs))
+	for _, pair := range pairs {
+		fmt.Printf("  %.0f%% similar:\n", pair.Similarity*100)
+		fmt.Printf("    %q (%s)\n", pair.First.Title, pair.First.URL)
+		fmt.Printf("    %q (%s)\n", pair.Second.Title, pair.Second.URL)
+	}
+}
+
+// articleIndex looks up articles by slug and canonicalized URL
+type articleIndex struct {
+	slugs        map[string]bool
+	urls         map[string]bool
+	foldPathCase bool
+}
+
+// newArticleIndex indexes the slugs and URLs of the articles. With foldPathCase, URLs whose
+// paths only differ in case are considered the same.
+func newArticleIndex(articles []OGMetadata, foldPathCase bool) *articleIndex {
+	index := &articleIndex{
+		slugs:        map[string]bool{},
+		urls:         map[string]bool{},
+		foldPathCase: foldPathCase,
+	}
+	for _, article := range articles {
+		if article.Slug != "" {
+			index.slugs[article.Slug] = true
+		}
+		if article.URL != "" {
+			index.urls[articlesjson.CanonicalizeURL(article.URL, foldPathCase)] = true
+		}
+	}
+	return index
+}
+
+// contains reports whether an article with the same slug or URL is indexed
+func (index *articleIndex) contains(article OGMetadata) bool {
+	if article.Slug != "" && index.slugs[article.Slug] {
+		return true
+	}
+	return article.URL != "" && index.urls[articlesjson.CanonicalizeURL(article.URL, index.foldPathCase)]
+}
+
+// fetchMasterIndex downloads a master articles.json and indexes its articles
+func fetchMasterIndex(ctx context.Context, masterURL string, opts Options) (*articleIndex, error) {
+	resp, err := fetchPage(ctx, masterURL, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch master list: status code %d", resp.StatusCode)
+	}
+
+	var master ArticlesCollection
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&master)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON format in master list: %w", err)
+	}
+
+	return newArticleIndex(master.Articles, opts.FoldURLCase), nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestFindSimilarTitles(t *testing.T) {
+	articles := []OGMetadata{
+		{URL: "https://blog.example/a", Title: "Profiling Go Services in Production"},
+		{URL: "https://blog.example/b", Title: "Building a Blog with Vibe Coding"},
+		{URL: "https://mirror.example/a", Title: "Profiling Go services in production!"},
+		{URL: "https://blog.example/c"},
+		{URL: "https://blog.example/d"},
+	}
+
+	pairs := findSimilarTitles(articles, 0.9)
+	if len(pairs) != 1 {
+		t.Fatalf("got %d pairs, want 1: %+v", len(pairs), pairs)
+	}
+	if pairs[0].First.URL != "https://blog.example/a" || pairs[0].Second.URL != "https://mirror.example/a" {
+		t.Errorf("flagged %s and %s, want the two profiling articles", pairs[0].First.URL, pairs[0].Second.URL)
+	}
+	if pairs[0].Similarity < 0.9 || pairs[0].Similarity > 1 {
+		t.Errorf("similarity = %v, want between 0.9 and 1", pairs[0].Similarity)
+	}
+}
+
+func TestDedupeAgainstMaster(t *testing.T) {
+	server := newFixtureServer(t)
+	masterURL := server.handle("/master.json", fixture{
+		Body:   `{"articles": [{"url": "https://elsewhere.example/vibe-coding", "slug": "vibe-coding"}]}`,
+		Header: http.Header{"Content-Type": {"application/json"}},
+	})
+	known := server.handle("/blog/vibe-coding", fixture{File: "article.html"})
+	unknown := server.handle("/blog/profiling", fixture{File: "article.html"})
+
+	opts := Options{Quiet: true, Mode: "append"}
+	index, err := fetchMasterIndex(context.Background(), masterURL, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.MasterIndex = index
+
+	output := writeArticles(t, "articles.json")
+	for _, url := range []string{known, unknown} {
+		if _, err := processURL(context.Background(), url, output, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := articleSlugs(readArticles(t, output)); !slices.Equal(got, []string{"profiling"}) {
+		t.Errorf("imported slugs = %v, want only [profiling]", got)
+	}
+}
+
+func TestArticleIndexNormalizesURLs(t *testing.T) {
+	articles := []OGMetadata{{URL: "https://blog.example/posts/Profiling", Slug: "profiling"}}
+
+	tests := []struct {
+		name         string
+		url          string
+		foldPathCase bool
+		want         bool
+	}{
+		{"same URL", "https://blog.example/posts/Profiling", false, true},
+		{"trailing slash", "https://blog.example/posts/Profiling/", false, true},
+		{"host case", "https://Blog.Example/posts/Profiling", false, true},
+		{"scheme case", "HTTPS://blog.example/posts/Profiling", false, true},
+		{"default port", "https://blog.example:443/posts/Profiling", false, true},
+		{"fragment", "https://blog.example/posts/Profiling#comments", false, true},
+		{"path case", "https://blog.example/posts/profiling", false, false},
+		{"path case folded", "https://blog.example/posts/profiling", true, true},
+		{"other port", "https://blog.example:8443/posts/Profiling", false, false},
+		{"other path", "https://blog.example/posts/tracing", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			index := newArticleIndex(articles, tt.foldPathCase)
+			if got := index.contains(OGMetadata{URL: tt.url, Slug: "new-slug"}); got != tt.want {
+				t.Errorf("contains(%q) = %v, want %v", tt.url, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// minDescriptionLength is the number of characters below which a description is too short to
+// say anything about the article
+const minDescriptionLength = 20
+
+// excerptLength is the maximum length of a description taken from the article text
+const excerptLength = 200
+
+// placeholderDescriptions are descriptions that sites use instead of a summary, compared
+// ignoring case, punctuation and ellipses
+var placeholderDescriptions = map[string]bool{
+	"click here to read more":  true,
+	"click here":               true,
+	"read more":                true,
+	"read more here":           true,
+	"continue reading":         true,
+	"more":                     true,
+	"description":              true,
+	"no description":           true,
+	"no description available": true,
+	"undefined":                true,
+	"null":                     true,
+	"n a":                      true,
+	"coming soon":              true,
+	"lorem ipsum":              true,
+}
+
+// isPlaceholderDescription reports whether the description is obviously not a summary of the
+// article: only an ellipsis, very short, or a known placeholder phrase
+func isPlaceholderDescription(description string) bool {
+	words := strings.FieldsFunc(strings.ToLower(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	phrase := strings.Join(words, " ")
+
+	if phrase == "" || placeholderDescriptions[phrase] || strings.HasPrefix(phrase, "lorem ipsum") {
+		return true
+	}
+	return utf8.RuneCountInString(strings.TrimSpace(description)) < minDescriptionLength
+}
+
+// checkDescription warns about a placeholder description. With -strict, it is treated as
+// missing, and a missing description is replaced by the description meta tag, the Twitter
+// card description or, failing those, an excerpt of the first paragraph of the article.
+func checkDescription(doc *html.Node, properties map[string]string, metadata *OGMetadata, url string, opts Options) {
+	if metadata.Description != "" && isPlaceholderDescription(metadata.Description) {
+		warnf("%s: description %q looks like a placeholder", url, metadata.Description)
+		if !opts.Strict {
+			return
+		}
+		metadata.Description = ""
+	}
+
+	if metadata.Description != "" || !opts.Strict {
+		return
+	}
+
+	for _, property := range []string{"description", "twitter:description"} {
+		if description := strings.TrimSpace(properties[property]); description != "" && !isPlaceholderDescription(description) {
+			metadata.Description = description
+			return
+		}
+	}
+
+	metadata.Description = articleExcerpt(doc)
+}
+
+// articleExcerpt returns the first paragraph of the article content that isn't a placeholder,
+// cut at a word boundary, or an empty string if there is none
+func articleExcerpt(doc *html.Node) string {
+	// Prefer the article content over the rest of the page, such as navigation and banners
+	content := findElement(doc, func(n *html.Node) bool { return n.Data == "article" })
+	if content == nil {
+		content = findElement(doc, func(n *html.Node) bool { return n.Data == "main" })
+	}
+	if content == nil {
+		content = doc
+	}
+
+	var excerpt string
+	findElement(content, func(n *html.Node) bool {
+		if n.Data != "p" {
+			return false
+		}
+		text := elementText(n)
+		if isPlaceholderDescription(text) {
+			return false
+		}
+		excerpt = truncateAtWord(text, excerptLength)
+		return true
+	})
+	return excerpt
+}
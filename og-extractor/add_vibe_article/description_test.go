@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlaceholderDescription(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		page       string
+		strict     bool
+		want       string
+		wantWarned bool
+	}{
+		{"placeholder-description.html", false, "Click here to read more...", true},
+		// With -strict, the description meta tag replaces the placeholder
+		{"placeholder-description.html", true, "Finding the hot paths of a Go service with pprof.", true},
+		{"placeholder-excerpt.html", false, "…", true},
+		// The meta tag is a placeholder too, so the first real paragraph of the article is used
+		{"placeholder-excerpt.html", true, "Our API server got slower with every release, so we finally sat down with pprof and a flame graph.", true},
+		{"article.html", true, "How we built our blog by describing it to an assistant instead of writing it.", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			metadata := extractPage(t, server.page(tt.page), Options{Strict: tt.strict})
+			if metadata.Description != tt.want {
+				t.Errorf("description = %q, want %q", metadata.Description, tt.want)
+			}
+
+			args := []string{"-quiet", server.page(tt.page), writeArticles(t, "articles.json")}
+			if tt.strict {
+				args = append([]string{"-strict"}, args...)
+			}
+			result := runCLI(t, args...)
+			if result.code != 0 {
+				t.Fatalf("exit code %d, stderr: %s", result.code, result.stderr)
+			}
+			if warned := strings.Contains(result.stderr, "looks like a placeholder"); warned != tt.wantWarned {
+				t.Errorf("stderr = %q, want the placeholder warning: %v", result.stderr, tt.wantWarned)
+			}
+		})
+	}
+}
+
+func TestIsPlaceholderDescription(t *testing.T) {
+	tests := []struct {
+		description string
+		want        bool
+	}{
+		{"Read more…", true},
+		{"CLICK HERE!", true},
+		{"...", true},
+		{"N/A", true},
+		{"Lorem ipsum dolor sit amet, consectetur adipiscing elit.", true},
+		{"Too short", true},
+		{"Finding the hot paths of a Go service with pprof.", false},
+		{"Read more about profiling Go services with pprof.", false},
+	}
+
+	for _, tt := range tests {
+		if got := isPlaceholderDescription(tt.description); got != tt.want {
+			t.Errorf("isPlaceholderDescription(%q) = %v, want %v", tt.description, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// FailureRecord describes a URL that couldn't be imported, so it can be triaged and retried
+type FailureRecord struct {
+	URL      string `json:"url"`
+	Error    string `json:"error"`
+	FailedAt string `json:"failedAt"`
+}
+
+// FailuresCollection represents the structure of the failures file
+type FailuresCollection struct {
+	Failures []FailureRecord `json:"failures"`
+}
+
+// recordFailure appends a record of the failed URL to the failures file, creating it if needed
+func recordFailure(filePath, url string, failure error, mode os.FileMode) error {
+	collection := FailuresCollection{
+		Failures: []FailureRecord{},
+	}
+
+	fileContent, err := ioutil.ReadFile(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read failures file: %w", err)
+	}
+	if len(fileContent) > 0 {
+		err = json.Unmarshal(fileContent, &collection)
+		if err != nil {
+			return fmt.Errorf("invalid JSON format in failures file: %w", err)
+		}
+	}
+
+	collection.Failures = append(collection.Failures, FailureRecord{
+		URL:      url,
+		Error:    failure.Error(),
+		FailedAt: time.Now().UTC().Format(time.RFC3339),
+	})
+
+	jsonData, err := json.MarshalIndent(collection, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	return writeFileWithMode(filePath, append(jsonData, '\n'), mode)
+}
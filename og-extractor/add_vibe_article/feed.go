@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+)
+
+// rssFeed is the structure of an RSS 2.0 document
+type rssFeed struct {
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Enclosure   struct {
+		URL  string `xml:"url,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"enclosure"`
+	MediaContent []struct {
+		URL    string `xml:"url,attr"`
+		Medium string `xml:"medium,attr"`
+		Type   string `xml:"type,attr"`
+	} `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaThumbnail struct {
+		URL string `xml:"url,attr"`
+	} `xml:"http://search.yahoo.com/mrss/ thumbnail"`
+}
+
+// atomFeed is the structure of an Atom document
+type atomFeed struct {
+	Title   string      `xml:"title"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title     string `xml:"title"`
+	Summary   string `xml:"summary"`
+	Content   string `xml:"content"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+	Links     []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+		Type string `xml:"type,attr"`
+	} `xml:"link"`
+	Authors []struct {
+		Name string `xml:"name"`
+	} `xml:"author"`
+}
+
+// isFeed reports whether a response is an RSS or Atom feed, based on its content type
+// or, for servers sending a generic type, on the root element of the document
+func isFeed(contentType string, body []byte) bool {
+	contentType = strings.ToLower(contentType)
+	if strings.Contains(contentType, "rss+xml") || strings.Contains(contentType, "atom+xml") {
+		return true
+	}
+
+	root := feedRootElement(body)
+	return root == "rss" || root == "feed"
+}
+
+// feedRootElement returns the name of the root element of an XML document, or an empty
+// string if the body doesn't look like XML (HTML pages are never parsed as XML)
+func feedRootElement(body []byte) string {
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if !bytes.HasPrefix(trimmed, []byte("<?xml")) && !bytes.HasPrefix(trimmed, []byte("<rss")) && !bytes.HasPrefix(trimmed, []byte("<feed")) {
+		return ""
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(trimmed))
+	decoder.Strict = false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local
+		}
+	}
+}
+
+// extractFeedArticles converts each item of an RSS feed or entry of an Atom feed to an article
+func extractFeedArticles(body []byte, feedURL string, opts Options) ([]OGMetadata, error) {
+	var articles []OGMetadata
+
+	if feedRootElement(body) == "feed" {
+		var feed atomFeed
+		err := unmarshalFeed(body, &feed)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entry := range feed.Entries {
+			metadata := OGMetadata{
+				URL:         resolveURL(feedURL, atomEntryLink(entry)),
+				Title:       strings.TrimSpace(entry.Title),
+				Description: strings.TrimSpace(firstNonEmpty(entry.Summary, entry.Content)),
+				PublishDate: strings.TrimSpace(firstNonEmpty(entry.Published, entry.Updated)),
+				Source:      strings.TrimSpace(feed.Title),
+			}
+			var authors []string
+			for _, author := range entry.Authors {
+				if name := strings.TrimSpace(author.Name); name != "" {
+					authors = append(authors, name)
+				}
+			}
+			metadata.Author = strings.Join(authors, ", ")
+			for _, link := range entry.Links {
+				if link.Rel == "enclosure" && strings.HasPrefix(link.Type, "image/") {
+					metadata.Image = resolveURL(feedURL, link.Href)
+					break
+				}
+			}
+			articles = append(articles, finishFeedArticle(metadata, opts))
+		}
+
+		return articles, nil
+	}
+
+	var feed rssFeed
+	err := unmarshalFeed(body, &feed)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range feed.Channel.Items {
+		metadata := OGMetadata{
+			URL:         resolveURL(feedURL, strings.TrimSpace(item.Link)),
+			Title:       strings.TrimSpace(item.Title),
+			Description: strings.TrimSpace(item.Description),
+			PublishDate: strings.TrimSpace(firstNonEmpty(item.PubDate, item.Date)),
+			Source:      strings.TrimSpace(feed.Channel.Title),
+			Author:      strings.TrimSpace(item.Creator),
+		}
+		metadata.Image = rssItemImage(item)
+		articles = append(articles, finishFeedArticle(metadata, opts))
+	}
+
+	return articles, nil
+}
+
+// unmarshalFeed decodes a feed leniently, since feeds often contain HTML entities
+func unmarshalFeed(body []byte, feed interface{}) error {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	return decoder.Decode(feed)
+}
+
+// finishFeedArticle derives the slug of a feed article and applies the output options
+func finishFeedArticle(metadata OGMetadata, opts Options) OGMetadata {
+	metadata.Slug = extractSlug(metadata.URL)
+	if slug, err := slugFor(metadata.URL, opts); err == nil {
+		metadata.Slug = slug
+	}
+
+	applyOutputOptions(&metadata, opts)
+	return metadata
+}
+
+// atomEntryLink returns the alternate link of an Atom entry, or its first link
+func atomEntryLink(entry atomEntry) string {
+	for _, link := range entry.Links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	if len(entry.Links) > 0 {
+		return strings.TrimSpace(entry.Links[0].Href)
+	}
+	return ""
+}
+
+// rssItemImage returns the image of an RSS item from its enclosure or Media RSS elements
+func rssItemImage(item rssItem) string {
+	if strings.HasPrefix(item.Enclosure.Type, "image/") {
+		return item.Enclosure.URL
+	}
+	for _, content := range item.MediaContent {
+		if content.Medium == "image" || strings.HasPrefix(content.Type, "image/") {
+			return content.URL
+		}
+	}
+	return item.MediaThumbnail.URL
+}
+
+// firstNonEmpty returns the first of the values that isn't blank
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRSSFeed(t *testing.T) {
+	server := newFixtureServer(t)
+
+	articles, err := extractArticles(context.Background(), server.page("feed.rss"), Options{Quiet: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []OGMetadata{
+		{
+			URL:         "https://blog.example/posts/profiling-go",
+			Title:       "Profiling Go Services",
+			Description: "Finding the hot paths with pprof.",
+			PublishDate: "Thu, 02 May 2024 08:00:00 GMT",
+			Author:      "Ada Example",
+			Image:       "https://blog.example/images/profiling.png",
+			Slug:        "profiling-go",
+		},
+		{
+			// Relative links are resolved against the feed
+			URL:         server.URL + "/posts/go-generics",
+			Title:       "Notes on Go Generics",
+			Description: "What changed once generics landed in Go.",
+			PublishDate: "2024-04-18T10:00:00Z",
+			Image:       "https://blog.example/images/generics.png",
+			Slug:        "go-generics",
+		},
+		{
+			URL:         "https://blog.example/posts/vibe-coding",
+			Title:       "Building a Blog with Vibe Coding",
+			PublishDate: "Fri, 15 Mar 2024 09:30:00 GMT",
+			Slug:        "vibe-coding",
+		},
+	}
+	if len(articles) != len(want) {
+		t.Fatalf("got %d articles, want %d: %+v", len(articles), len(want), articles)
+	}
+	for i, article := range articles {
+		got := OGMetadata{
+			URL:         article.URL,
+			Title:       article.Title,
+			Description: article.Description,
+			PublishDate: article.PublishDate,
+			Author:      article.Author,
+			Image:       article.Image,
+			Slug:        article.Slug,
+		}
+		if !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("article %d = %+v, want %+v", i, got, want[i])
+		}
+		if article.Source != "Example Blog" {
+			t.Errorf("article %d source = %q, want the channel title", i, article.Source)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fixtureDir holds the pages served by the fixture server
+const fixtureDir = "testdata/fixtures"
+
+// fixture configures the response of the fixture server for one path. The zero value serves
+// the file of fixtureDir with the same name as the path, with status 200.
+type fixture struct {
+	// File is the file of fixtureDir served as the body, Body the body itself when File is empty
+	File string
+	Body string
+
+	// Status is the status code, 200 when zero
+	Status int
+
+	// Header holds the response headers, set before the Content-Type guessed from the file name
+	Header http.Header
+
+	// Delay is waited before the headers are written, and BodyDelay between the first half of
+	// the body and the rest, unless the client gives up first
+	Delay     time.Duration
+	BodyDelay time.Duration
+
+	// Choose, when set, picks the response for each request instead, for content negotiation
+	Choose func(r *http.Request) fixture
+}
+
+// fixtureServer is an httptest.Server serving fixture pages, recording the requests it gets
+type fixtureServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	fixtures map[string]fixture
+	requests map[string][]*http.Request
+}
+
+// newFixtureServer starts a fixture server, closed when the test ends
+func newFixtureServer(t *testing.T) *fixtureServer {
+	t.Helper()
+	server := &fixtureServer{
+		fixtures: map[string]fixture{},
+		requests: map[string][]*http.Request{},
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.serve))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// handle configures the response for the path and returns its URL
+func (server *fixtureServer) handle(path string, f fixture) string {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.fixtures[path] = f
+	return server.URL + path
+}
+
+// page returns the URL of a fixture file served with the default response
+func (server *fixtureServer) page(name string) string {
+	return server.URL + "/" + name
+}
+
+// requestsTo returns the requests made to the path so far
+func (server *fixtureServer) requestsTo(path string) []*http.Request {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	return append([]*http.Request(nil), server.requests[path]...)
+}
+
+func (server *fixtureServer) serve(w http.ResponseWriter, r *http.Request) {
+	server.mu.Lock()
+	server.requests[r.URL.Path] = append(server.requests[r.URL.Path], r)
+	f, ok := server.fixtures[r.URL.Path]
+	server.mu.Unlock()
+
+	if !ok {
+		f.File = strings.TrimPrefix(r.URL.Path, "/")
+	}
+	if f.Choose != nil {
+		f = f.Choose(r)
+	}
+
+	body := []byte(f.Body)
+	if f.File != "" {
+		content, err := os.ReadFile(filepath.Join(fixtureDir, filepath.FromSlash(f.File)))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		body = content
+	}
+
+	if !wait(r, f.Delay) {
+		return
+	}
+
+	for key, values := range f.Header {
+		w.Header()[key] = values
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", fixtureContentType(f.File))
+	}
+	status := f.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+
+	if f.BodyDelay == 0 {
+		w.Write(body)
+		return
+	}
+	half := len(body) / 2
+	w.Write(body[:half])
+	w.(http.Flusher).Flush()
+	if wait(r, f.BodyDelay) {
+		w.Write(body[half:])
+	}
+}
+
+// wait waits for the delay, returning false if the client gave up first
+func wait(r *http.Request, delay time.Duration) bool {
+	if delay == 0 {
+		return true
+	}
+	select {
+	case <-time.After(delay):
+		return true
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// fixtureContentType returns the Content-Type of a fixture file from its extension
+func fixtureContentType(name string) string {
+	switch filepath.Ext(name) {
+	case ".json":
+		return "application/json"
+	case ".xml", ".rss":
+		return "application/rss+xml"
+	case ".xhtml":
+		return "application/xhtml+xml"
+	}
+	return "text/html; charset=utf-8"
+}
+
+func TestFixtureServer(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		name       string
+		url        string
+		timeout    time.Duration
+		wantStatus int
+		wantHeader map[string]string
+		wantBody   string
+		wantErr    bool
+		minElapsed time.Duration
+	}{
+		{
+			name:       "fixture file",
+			url:        server.page("article.html"),
+			wantStatus: http.StatusOK,
+			wantHeader: map[string]string{"Content-Type": "text/html; charset=utf-8"},
+			wantBody:   "Building a Blog with Vibe Coding",
+		},
+		{
+			name:       "missing fixture",
+			url:        server.page("missing.html"),
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "status",
+			url:        server.handle("/gone", fixture{File: "article.html", Status: http.StatusGone}),
+			wantStatus: http.StatusGone,
+			wantBody:   "Building a Blog with Vibe Coding",
+		},
+		{
+			name: "headers",
+			url: server.handle("/headers", fixture{Body: "{}", Header: http.Header{
+				"Content-Type":     {"application/json"},
+				"Content-Language": {"fr-CA"},
+			}}),
+			wantStatus: http.StatusOK,
+			wantHeader: map[string]string{"Content-Type": "application/json", "Content-Language": "fr-CA"},
+			wantBody:   "{}",
+		},
+		{
+			name:       "delay",
+			url:        server.handle("/slow", fixture{Body: "late", Delay: 50 * time.Millisecond}),
+			wantStatus: http.StatusOK,
+			wantBody:   "late",
+			minElapsed: 50 * time.Millisecond,
+		},
+		{
+			name:    "delay past the client timeout",
+			url:     server.handle("/too-slow", fixture{Body: "late", Delay: time.Second}),
+			timeout: 50 * time.Millisecond,
+			wantErr: true,
+		},
+		{
+			name:       "body delay",
+			url:        server.handle("/slow-body", fixture{Body: "first half, second half", BodyDelay: 50 * time.Millisecond}),
+			wantStatus: http.StatusOK,
+			wantBody:   "first half, second half",
+			minElapsed: 50 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &http.Client{Timeout: tt.timeout}
+			start := time.Now()
+			resp, err := client.Get(tt.url)
+			if tt.wantErr {
+				if err == nil {
+					resp.Body.Close()
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			body := new(strings.Builder)
+			if _, err := io.Copy(body, resp.Body); err != nil {
+				t.Fatal(err)
+			}
+			if elapsed := time.Since(start); elapsed < tt.minElapsed {
+				t.Errorf("answered after %v, want at least %v", elapsed, tt.minElapsed)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			for key, want := range tt.wantHeader {
+				if got := resp.Header.Get(key); got != want {
+					t.Errorf("header %s = %q, want %q", key, got, want)
+				}
+			}
+			if !strings.Contains(body.String(), tt.wantBody) {
+				t.Errorf("body = %q, want it to contain %q", body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestFixtureServerRecordsRequests(t *testing.T) {
+	server := newFixtureServer(t)
+	url := server.page("article.html")
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept-Language", "de")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	requests := server.requestsTo("/article.html")
+	if len(requests) != 1 {
+		t.Fatalf("recorded %d requests, want 1", len(requests))
+	}
+	if got := requests[0].Header.Get("Accept-Language"); got != "de" {
+		t.Errorf("Accept-Language = %q, want %q", got, "de")
+	}
+}
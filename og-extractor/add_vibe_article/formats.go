@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// formatSniffBytes is how much of an existing output file detectFileFormat reads, so a large
+// file isn't read whole on every append
+const formatSniffBytes = 64 << 10
+
+// detectFileFormat returns the format of an existing output file: "json" for an
+// {"articles":[...]} file, "ndjson", "csv" or "yaml", based on its extension and first bytes.
+// It returns an empty string when the file doesn't exist, is empty or isn't recognized.
+func detectFileFormat(filePath string) string {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	content := make([]byte, formatSniffBytes)
+	n, err := io.ReadFull(file, content)
+	complete := err == io.EOF || err == io.ErrUnexpectedEOF
+	if err != nil && !complete {
+		return ""
+	}
+	content = content[:n]
+	if len(bytes.TrimSpace(content)) == 0 {
+		return ""
+	}
+
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".ndjson", ".jsonl":
+		return "ndjson"
+	case ".csv":
+		return "csv"
+	case ".yaml", ".yml":
+		return "yaml"
+	}
+
+	trimmed := bytes.TrimLeft(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")), " \t\r\n")
+	if len(trimmed) == 0 {
+		return ""
+	}
+	if isArticlesArrayStart(trimmed) {
+		return "json"
+	}
+
+	if trimmed[0] == '{' {
+		if isNDJSON(trimmed, complete) {
+			return "ndjson"
+		}
+		return "json"
+	}
+
+	if bytes.HasPrefix(trimmed, []byte("articles:")) || bytes.HasPrefix(trimmed, []byte("---")) {
+		return "yaml"
+	}
+
+	return ""
+}
+
+// isNDJSON reports whether the start of a file holds one JSON object per line. A file that is a
+// single JSON document, even a compact one on one line, isn't. When only a prefix of the file was
+// read, its last line may be cut off, so only the lines before it are checked and the file must
+// have at least two lines.
+func isNDJSON(content []byte, complete bool) bool {
+	if complete && json.Valid(content) {
+		return false
+	}
+
+	lines := bytes.Split(content, []byte("\n"))
+	if !complete {
+		if len(lines) < 2 {
+			return false
+		}
+		lines = lines[:len(lines)-1]
+	}
+
+	objects := 0
+	for _, line := range lines {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		var article map[string]interface{}
+		if json.Unmarshal(line, &article) != nil {
+			return false
+		}
+		objects++
+	}
+	return objects > 0
+}
+
+// appendInFormat appends the article to an existing file in the given format, after backing it up
+func appendInFormat(metadata OGMetadata, filePath, format string, mode os.FileMode) error {
+	backupPath := createBackupPath(filePath)
+	err := createBackupFile(filePath, backupPath, mode)
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	switch format {
+	case "ndjson":
+		return appendToNDJSONFile(metadata, filePath, mode)
+	case "csv":
+		return appendToCSVFile(metadata, filePath, mode)
+	case "yaml":
+		return appendToYAMLFile(metadata, filePath, mode)
+	}
+	return fmt.Errorf("unsupported file format %q", format)
+}
+
+// appendToNDJSONFile adds the article as a new line of a newline-delimited JSON file
+func appendToNDJSONFile(metadata OGMetadata, filePath string, mode os.FileMode) error {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read existing file: %w", err)
+	}
+
+	line, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		content = append(content, '\n')
+	}
+	content = append(append(content, line...), '\n')
+
+	return writeFileWithMode(filePath, content, mode)
+}
+
+// appendToCSVFile adds the article as a new row of a CSV file, filling the columns named
+// in its header row after the JSON field names. Unknown columns are left empty.
+func appendToCSVFile(metadata OGMetadata, filePath string, mode os.FileMode) error {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read existing file: %w", err)
+	}
+
+	header, err := csv.NewReader(bytes.NewReader(content)).Read()
+	if err != nil {
+		return fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	fields := map[string]reflect.Value{}
+	values := reflect.ValueOf(metadata)
+	for _, column := range sqliteColumns() {
+		fields[column.Name] = values.Field(column.Field)
+	}
+
+	row := make([]string, len(header))
+	for i, name := range header {
+		field, ok := fields[strings.TrimSpace(name)]
+		if !ok {
+			continue
+		}
+		value, err := sqliteValue(field)
+		if err != nil {
+			return fmt.Errorf("failed to format %s: %w", name, err)
+		}
+		if value != nil {
+			row[i] = fmt.Sprint(value)
+		}
+	}
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	writer.Write(row)
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to format CSV row: %w", err)
+	}
+
+	if len(content) > 0 && content[len(content)-1] != '\n' {
+		content = append(content, '\n')
+	}
+
+	return writeFileWithMode(filePath, append(content, buf.Bytes()...), mode)
+}
+
+// appendToYAMLFile adds the article to the "articles" list of a YAML file, keeping the
+// fields in the same order as the JSON output
+func appendToYAMLFile(metadata OGMetadata, filePath string, mode os.FileMode) error {
+	content, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read existing file: %w", err)
+	}
+
+	var doc yaml.Node
+	err = yaml.Unmarshal(content, &doc)
+	if err != nil {
+		return fmt.Errorf("invalid YAML format in existing file: %w", err)
+	}
+
+	articles := yamlArticlesNode(&doc)
+	if articles == nil {
+		return fmt.Errorf("no articles list in existing file")
+	}
+
+	// JSON is valid YAML, so decoding it keeps the field order
+	jsonData, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	var article yaml.Node
+	err = yaml.Unmarshal(jsonData, &article)
+	if err != nil {
+		return fmt.Errorf("failed to convert article to YAML: %w", err)
+	}
+	clearYAMLStyle(&article)
+	articles.Content = append(articles.Content, article.Content[0])
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	err = encoder.Encode(&doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	encoder.Close()
+
+	return writeFileWithMode(filePath, buf.Bytes(), mode)
+}
+
+// yamlArticlesNode returns the sequence node under the top-level "articles" key
+func yamlArticlesNode(doc *yaml.Node) *yaml.Node {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil
+	}
+
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "articles" {
+			value := root.Content[i+1]
+			// An empty "articles:" key is a null scalar
+			if value.Kind == yaml.ScalarNode && value.Tag == "!!null" {
+				*value = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			}
+			if value.Kind == yaml.SequenceNode {
+				return value
+			}
+		}
+	}
+	return nil
+}
+
+// clearYAMLStyle switches the node from the JSON flow style to the default block style,
+// keeping quotes on the strings that would otherwise be read as another type (such as "yes")
+func clearYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!str" {
+		if plain, err := yaml.Marshal(node.Value); err == nil && (plain[0] == '"' || plain[0] == '\'') {
+			node.Style = yaml.DoubleQuotedStyle
+		}
+	}
+	for _, child := range node.Content {
+		clearYAMLStyle(child)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDetectFileFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+	}{
+		{"missing file", "articles.json", "", ""},
+		{"empty file", "articles.json", "\n", ""},
+		{"only a byte order mark", "articles.json", "\xef\xbb\xbf", ""},
+		{"byte order mark and whitespace", "articles.json", "\xef\xbb\xbf \n\t", ""},
+		{"articles collection", "articles.json", "{\n  \"articles\": []\n}\n", "json"},
+		{"compact articles collection", "articles.json", `{"articles":[{"url":"https://example.com/a"}]}`, "json"},
+		{"compact grouped collection", "articles.json", `{"sources":{"X":[{"url":"https://example.com/a"}]}}` + "\n", "json"},
+		{"indented grouped collection", "articles.json", "{\n  \"sources\": {}\n}\n", "json"},
+		{"one article per line", "articles.json", `{"url":"https://example.com/a"}` + "\n" + `{"url":"https://example.com/b"}` + "\n", "ndjson"},
+		{"blank line between articles", "articles.json", `{"url":"https://example.com/a"}` + "\n\n" + `{"url":"https://example.com/b"}`, "ndjson"},
+		{"ndjson extension", "articles.jsonl", `{"url":"https://example.com/a"}`, "ndjson"},
+		{"csv extension", "articles.csv", "url,title\n", "csv"},
+		{"yaml content", "articles", "articles:\n  - url: https://example.com/a\n", "yaml"},
+		{"unrecognized", "articles.txt", "hello\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), tt.file)
+			if tt.name != "missing file" {
+				if err := os.WriteFile(filePath, []byte(tt.content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := detectFileFormat(filePath); got != tt.want {
+				t.Errorf("detectFileFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectFileFormatReadsAPrefix(t *testing.T) {
+	line := `{"url":"https://example.com/a","description":"` + strings.Repeat("x", 1000) + `"}` + "\n"
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"large ndjson file", strings.Repeat(line, 2*formatSniffBytes/len(line)), "ndjson"},
+		{"large compact json file", `{"sources":{"X":[` + strings.Repeat(`{"url":"https://example.com/a"},`, formatSniffBytes/10) + `{}]}}`, "json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "articles.json")
+			if err := os.WriteFile(filePath, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if got := detectFileFormat(filePath); got != tt.want {
+				t.Errorf("detectFileFormat() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendInDetectedFormat(t *testing.T) {
+	server := newFixtureServer(t)
+	url := server.handle("/blog/vibe-coding", fixture{File: "article.html"})
+
+	tests := []struct {
+		name     string
+		file     string
+		existing string
+		want     string
+	}{
+		{
+			name:     "ndjson",
+			file:     "articles.json",
+			existing: `{"url":"https://blog.example/a","slug":"a"}` + "\n" + `{"url":"https://blog.example/b","slug":"b"}`,
+			want: `{"url":"https://blog.example/a","slug":"a"}` + "\n" + `{"url":"https://blog.example/b","slug":"b"}` + "\n" +
+				`{"url":"","title":"Building a Blog with Vibe Coding","description":"How we built our blog by describing it to an assistant instead of writing it.","type":"article","locale":"en","image":"https://blog.example/images/vibe.png","slug":"vibe-coding","publishDate":"2024-03-15T09:30:00Z","source":"Example Blog","author":"Ada Example"}` + "\n",
+		},
+		{
+			name:     "csv",
+			file:     "articles.csv",
+			existing: "slug,title,publishDate,unknown\na,First,2023-01-01,x\n",
+			want:     "slug,title,publishDate,unknown\na,First,2023-01-01,x\nvibe-coding,Building a Blog with Vibe Coding,2024-03-15T09:30:00Z,\n",
+		},
+		{
+			name:     "yaml",
+			file:     "articles.yaml",
+			existing: "# Imported articles\narticles:\n  - url: https://blog.example/a\n    slug: a\n",
+			want: `# Imported articles
+articles:
+  - url: https://blog.example/a
+    slug: a
+  - url: ""
+    title: Building a Blog with Vibe Coding
+    description: How we built our blog by describing it to an assistant instead of writing it.
+    type: article
+    locale: en
+    image: https://blog.example/images/vibe.png
+    slug: vibe-coding
+    publishDate: "2024-03-15T09:30:00Z"
+    source: Example Blog
+    author: Ada Example
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(output, []byte(tt.existing), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			result := runCLI(t, "-quiet", url, output)
+			if result.code != 0 {
+				t.Fatalf("exit code %d, stderr:\n%s", result.code, result.stderr)
+			}
+
+			got, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("file:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"add_vibe_article/articlesjson"
+
+	"golang.org/x/net/html"
+)
+
+// nuxtStatePrefix starts the script in which Nuxt 2 serializes the state of a server-rendered page
+const nuxtStatePrefix = "window.__NUXT__="
+
+// maxFrameworkDataNodes bounds the search of the page state, which can be large
+const maxFrameworkDataNodes = 10000
+
+// Keys under which the page state of JavaScript frameworks commonly holds the fields of a post,
+// in order of preference
+var (
+	frameworkTitleKeys       = []string{"title", "headline"}
+	frameworkDescriptionKeys = []string{"description", "excerpt", "summary", "subtitle"}
+	frameworkDateKeys        = []string{"publishedAt", "datePublished", "publishDate", "published_at", "publishedDate", "date", "createdAt", "created_at"}
+	frameworkAuthorKeys      = []string{"author", "authors", "authorName"}
+	frameworkImageKeys       = []string{"coverImage", "featuredImage", "heroImage", "ogImage", "image", "thumbnail"}
+	frameworkContentKeys     = []string{"content", "body", "slug", "excerpt"}
+)
+
+// frameworkDataScript returns the JSON state a server-rendered Next.js (__NEXT_DATA__) or
+// Nuxt (window.__NUXT__) page embeds in a script element, if the element is one
+func frameworkDataScript(n *html.Node) (string, bool) {
+	if n.FirstChild == nil {
+		return "", false
+	}
+	for _, attr := range n.Attr {
+		if attr.Key == "id" && attr.Val == "__NEXT_DATA__" {
+			return n.FirstChild.Data, true
+		}
+	}
+
+	// Only the state serialized as a literal can be read, not the one built by a function
+	text := strings.TrimSpace(n.FirstChild.Data)
+	if state, found := strings.CutPrefix(text, nuxtStatePrefix); found && strings.HasPrefix(state, "{") {
+		return strings.TrimSuffix(state, ";"), true
+	}
+	return "", false
+}
+
+// extractFromFrameworkData fills the fields the meta tags and JSON-LD data left empty from the
+// page state of a Next.js or Nuxt page, which often has cleaner values. The state is searched,
+// shallowest first, for the object that looks like the post: one with a title and a date, an
+// author or content.
+func extractFromFrameworkData(blocks []string, metadata *OGMetadata, url string, opts Options) {
+	for i, block := range blocks {
+		var data interface{}
+		err := json.Unmarshal([]byte(block), &data)
+		if err != nil {
+			if opts.Verbose {
+				fmt.Fprintf(os.Stderr, "%s: ignoring framework data block %d: %v\n", url, i+1, err)
+			}
+			continue
+		}
+
+		// The props of a Next.js page are those of the page itself, without the app's layout
+		if root, ok := data.(map[string]interface{}); ok {
+			if props, ok := root["props"].(map[string]interface{}); ok && props["pageProps"] != nil {
+				data = props["pageProps"]
+			}
+		}
+
+		post := findFrameworkPost(data)
+		if post == nil {
+			continue
+		}
+		if metadata.Title == "" {
+			metadata.Title = frameworkString(post, frameworkTitleKeys)
+		}
+		if metadata.Description == "" {
+			metadata.Description = frameworkString(post, frameworkDescriptionKeys)
+		}
+		if metadata.PublishDate == "" {
+			metadata.PublishDate = frameworkDate(post)
+		}
+		if metadata.Author == "" {
+			for _, key := range frameworkAuthorKeys {
+				if metadata.Author = authorFromJSONValue(post[key]); metadata.Author != "" {
+					break
+				}
+			}
+		}
+		if metadata.Image == "" {
+			metadata.Image = frameworkImage(post, url)
+		}
+		return
+	}
+}
+
+// findFrameworkPost returns the shallowest object of the page state that looks like a post
+func findFrameworkPost(data interface{}) map[string]interface{} {
+	queue := []interface{}{data}
+	for visited := 0; len(queue) > 0 && visited < maxFrameworkDataNodes; visited++ {
+		value := queue[0]
+		queue = queue[1:]
+
+		switch v := value.(type) {
+		case []interface{}:
+			queue = append(queue, v...)
+		case map[string]interface{}:
+			if frameworkString(v, frameworkTitleKeys) != "" && (frameworkDate(v) != "" ||
+				hasAnyKey(v, frameworkAuthorKeys) || hasAnyKey(v, frameworkContentKeys)) {
+				return v
+			}
+
+			// Map order is random, so the children are visited in the order of their keys
+			keys := make([]string, 0, len(v))
+			for key := range v {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				queue = append(queue, v[key])
+			}
+		}
+	}
+	return nil
+}
+
+// frameworkString returns the first non-empty string value of the object under one of the keys
+func frameworkString(object map[string]interface{}, keys []string) string {
+	for _, key := range keys {
+		if value, ok := object[key].(string); ok && strings.TrimSpace(value) != "" {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// frameworkDate returns the first value of the object under a date key that parses as a date,
+// since keys such as "date" are also used for other things
+func frameworkDate(object map[string]interface{}) string {
+	for _, key := range frameworkDateKeys {
+		if value, ok := object[key].(string); ok {
+			if _, valid := articlesjson.ParsePublishDate(value); valid {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// frameworkImage returns the absolute URL of the image of the post, given as a URL, an object
+// with a "url" or "src", or an array of either
+func frameworkImage(object map[string]interface{}, pageURL string) string {
+	for _, key := range frameworkImageKeys {
+		image := imageFromJSONValue(object[key])
+		if source, ok := object[key].(map[string]interface{}); ok && image == "" {
+			image, _ = source["src"].(string)
+		}
+		if image != "" {
+			return resolveURL(pageURL, image)
+		}
+	}
+	return ""
+}
+
+// hasAnyKey reports whether the object has a non-null value under one of the keys
+func hasAnyKey(object map[string]interface{}, keys []string) bool {
+	for _, key := range keys {
+		if object[key] != nil {
+			return true
+		}
+	}
+	return false
+}
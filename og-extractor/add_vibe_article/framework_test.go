@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFrameworkData(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		page string
+		want OGMetadata
+	}{
+		{"next-data.html", OGMetadata{
+			Title:       "Profiling Go Services",
+			Description: "Finding the hot paths with pprof.",
+			PublishDate: "2024-05-02T08:00:00Z",
+			Author:      "Ada Example",
+			Image:       server.URL + "/images/profiling.png",
+		}},
+		// The og:title is kept, the state only fills the missing fields
+		{"nuxt-data.html", OGMetadata{
+			Title:       "Profiling Go Services | Example Blog",
+			Description: "Finding the hot paths with pprof.",
+			PublishDate: "2024-05-02",
+			Author:      "Ada Example, Grace Example",
+			Image:       "https://cdn.example/profiling.png",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			metadata := extractPage(t, server.page(tt.page), Options{FrameworkData: true})
+			got := OGMetadata{
+				Title:       metadata.Title,
+				Description: metadata.Description,
+				PublishDate: metadata.PublishDate,
+				Author:      metadata.Author,
+				Image:       metadata.Image,
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("metadata = %+v, want %+v", got, tt.want)
+			}
+
+			// The page state is only read with -framework-data
+			metadata = extractPage(t, server.page(tt.page), Options{})
+			if metadata.Description != "" || metadata.PublishDate != "" || metadata.Author != "" {
+				t.Errorf("metadata = %+v without -framework-data, want the page state ignored", metadata)
+			}
+		})
+	}
+}
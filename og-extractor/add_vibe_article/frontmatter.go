@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// writeFrontMatterFile writes the article as <slug>.md in the output directory, with its fields
+// as YAML front matter and its description as the body, and returns the path of the file. With
+// -output-by-category, the file goes into a subdirectory named after the article's category.
+// An existing file for the same slug is replaced.
+func writeFrontMatterFile(metadata OGMetadata, outputDir string, opts Options) (string, error) {
+	if metadata.Slug == "" {
+		return "", fmt.Errorf("the article has no slug to name its file after")
+	}
+
+	dir := outputDir
+	if opts.OutputByCategory {
+		// Articles without a category stay at the top of the output directory
+		if name := categoryDirName(metadata.Category); name != "" {
+			dir = filepath.Join(dir, name)
+		}
+	}
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// JSON is valid YAML, so decoding it keeps the field order of the JSON output
+	jsonData, err := json.Marshal(metadata)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	var fields yaml.Node
+	err = yaml.Unmarshal(jsonData, &fields)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert article to YAML: %w", err)
+	}
+	clearYAMLStyle(&fields)
+
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	err = encoder.Encode(&fields)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal YAML: %w", err)
+	}
+	encoder.Close()
+	buf.WriteString("---\n")
+	if metadata.Description != "" {
+		buf.WriteString("\n" + metadata.Description + "\n")
+	}
+
+	path := filepath.Join(dir, metadata.Slug+".md")
+	return path, writeFileWithMode(path, buf.Bytes(), os.FileMode(opts.OutputMode))
+}
+
+// categoryDirName turns a category into a directory name: lowercase, with every run of characters
+// other than letters and digits replaced by a hyphen, so "Tech" gives "tech" and "Dev / Ops"
+// gives "dev-ops". A category without letters or digits gives an empty string.
+func categoryDirName(category string) string {
+	return strings.Trim(nonSlugCharacters.ReplaceAllString(strings.ToLower(category), "-"), "-")
+}
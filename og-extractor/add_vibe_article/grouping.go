@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// convertLayout rewrites the JSON file grouped by source or as a flat list of articles
+func convertLayout(filePath, layout string, opts Options) error {
+	if layout != "grouped" && layout != "flat" {
+		return fmt.Errorf("unknown layout %q, expected grouped or flat", layout)
+	}
+
+	collection, err := readCollection(filePath)
+	if err != nil {
+		return err
+	}
+
+	if collection.Grouped == (layout == "grouped") {
+		fmt.Printf("%s is already %s\n", filePath, layout)
+		return nil
+	}
+	collection.Grouped = layout == "grouped"
+
+	// Create backup before rewriting the file
+	backupPath := createBackupPath(filePath)
+	err = createBackupFile(filePath, backupPath, os.FileMode(opts.OutputMode))
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	err = writeCollection(collection, filePath, os.FileMode(opts.OutputMode), opts.Indent.Indent())
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Converted %s to the %s layout (%d article(s))\n", filePath, layout, len(collection.Articles))
+	return nil
+}
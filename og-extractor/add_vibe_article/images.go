@@ -0,0 +1,434 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// imageExtensions maps common image MIME types to file extensions
+var imageExtensions = map[string]string{
+	"image/png":     ".png",
+	"image/jpeg":    ".jpg",
+	"image/gif":     ".gif",
+	"image/webp":    ".webp",
+	"image/svg+xml": ".svg",
+	"image/avif":    ".avif",
+}
+
+// isDataURI reports whether the string is a data: URI
+func isDataURI(value string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(value)), "data:")
+}
+
+// handleDataImage replaces an inline data: URI image, which would bloat the output, with
+// either nothing or, when saving is enabled, the path of the decoded file in the image directory
+func handleDataImage(metadata *OGMetadata, opts Options) {
+	if !isDataURI(metadata.Image) {
+		return
+	}
+
+	if !opts.SaveDataImages {
+		warnf("skipping inline data: URI image for %s (use -save-data-images to keep it)", metadata.Slug)
+		metadata.Image = ""
+		return
+	}
+
+	path, err := saveDataImage(metadata.Image, metadata.ImageType, metadata.Slug, opts.ImageDir)
+	if err != nil {
+		warnf("failed to save inline image for %s: %v", metadata.Slug, err)
+		metadata.Image = ""
+		return
+	}
+	metadata.Image = path
+}
+
+// saveDataImage decodes a data: URI and writes it to <dir>/<name><ext>, returning the file path.
+// The extension comes from the media type of the URI or, when it has none, from the declared type.
+func saveDataImage(dataURI, declaredType, name, dir string) (string, error) {
+	header, payload, found := strings.Cut(strings.TrimSpace(dataURI)[len("data:"):], ",")
+	if !found {
+		return "", fmt.Errorf("malformed data URI")
+	}
+
+	// The header is "<mediatype>[;param=value]*[;base64]"
+	params := strings.Split(header, ";")
+	mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		mediaType = declaredType
+	}
+	isBase64 := strings.EqualFold(params[len(params)-1], "base64")
+
+	var data []byte
+	var err error
+	if isBase64 {
+		data, err = base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			// Some pages omit the padding
+			data, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(payload, "="))
+		}
+	} else {
+		var decoded string
+		decoded, err = url.PathUnescape(payload)
+		data = []byte(decoded)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to decode image data: %w", err)
+	}
+
+	ext := imageExtensions[mediaType]
+	if ext == "" {
+		if extensions, _ := mime.ExtensionsByType(mediaType); len(extensions) > 0 {
+			ext = extensions[0]
+		} else {
+			ext = ".bin"
+		}
+	}
+
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, name+ext)
+	err = ioutil.WriteFile(path, data, 0644)
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// trackingImageHints are substrings of image URLs that are usually tracking pixels or spacers
+var trackingImageHints = []string{"pixel", "tracking", "spacer", "blank.gif"}
+
+// findContentImage returns the absolute URL of the first <img> that is either declared at
+// least minSize pixels wide and high, or placed inside <article> without declared dimensions.
+// Tracking pixels, inline images and small icons are skipped.
+func findContentImage(doc *html.Node, pageURL string, minSize int) string {
+	var found string
+
+	var inspect func(n *html.Node, inArticle bool)
+	inspect = func(n *html.Node, inArticle bool) {
+		if found != "" {
+			return
+		}
+
+		if n.Type == html.ElementNode && n.Data == "article" {
+			inArticle = true
+		}
+
+		if n.Type == html.ElementNode && n.Data == "img" {
+			var src, lazySrc string
+			width, height := -1, -1
+			for _, attr := range n.Attr {
+				switch attr.Key {
+				case "src":
+					src = attr.Val
+				case "data-src":
+					lazySrc = attr.Val
+				case "width":
+					width = imageDimension(attr.Val)
+				case "height":
+					height = imageDimension(attr.Val)
+				}
+			}
+
+			// Lazy-loaded images keep a placeholder in src
+			if src == "" || isDataURI(src) {
+				src = lazySrc
+			}
+
+			if src != "" && !isDataURI(src) && !isTrackingImage(src) {
+				sized := width >= 0 && height >= 0
+				if (sized && width >= minSize && height >= minSize) || (!sized && inArticle) {
+					found = resolveURL(pageURL, src)
+					return
+				}
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			inspect(c, inArticle)
+		}
+	}
+	inspect(doc, false)
+
+	return found
+}
+
+// imageDimension parses a width or height attribute, returning -1 if it isn't a pixel value
+func imageDimension(value string) int {
+	size, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(value), "px"))
+	if err != nil {
+		return -1
+	}
+	return size
+}
+
+// isTrackingImage reports whether the image URL looks like a tracking pixel or spacer
+func isTrackingImage(src string) bool {
+	lower := strings.ToLower(src)
+	for _, hint := range trackingImageHints {
+		if strings.Contains(lower, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// rewriteImageURL replaces the prefix of the image URL according to the "old=new" rules,
+// applying the longest matching prefix
+func rewriteImageURL(image string, rules mappingFlag) string {
+	var match string
+	for prefix := range rules {
+		if strings.HasPrefix(image, prefix) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	if match == "" {
+		return image
+	}
+	return rules[match] + strings.TrimPrefix(image, match)
+}
+
+// imageResizingParams are the query parameters image CDNs use to resize, crop or recompress an
+// image on the fly. They don't change which image is served, unlike a version or an asset id.
+var imageResizingParams = map[string]bool{
+	"w": true, "h": true, "width": true, "height": true,
+	"q": true, "quality": true, "dpr": true,
+	"fit": true, "crop": true, "resize": true,
+	"auto": true, "fm": true, "format": true, "strip": true,
+}
+
+// imageSignatureParams sign the whole query on some CDNs, such as imgix, so it can't be changed
+var imageSignatureParams = map[string]bool{"s": true, "sig": true, "signature": true}
+
+// cleanImageURL removes the resizing parameters from the query of an image URL, keeping the other
+// ones as they are. A signed URL is left alone, since it would no longer be valid.
+func cleanImageURL(image string) string {
+	parsed, err := url.Parse(image)
+	if err != nil || parsed.RawQuery == "" {
+		return image
+	}
+
+	var kept []string
+	for _, pair := range strings.Split(parsed.RawQuery, "&") {
+		key, _, _ := strings.Cut(pair, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		name = strings.ToLower(name)
+
+		if imageSignatureParams[name] {
+			return image
+		}
+		if !imageResizingParams[name] {
+			kept = append(kept, pair)
+		}
+	}
+	parsed.RawQuery = strings.Join(kept, "&")
+	return parsed.String()
+}
+
+// cleanImageBlocks applies cleanImageURL to the image blocks, merging the blocks that then have the
+// same URL, such as one image listed in several sizes. Sub-properties missing from the first block
+// are taken from the next ones.
+func cleanImageBlocks(blocks []ImageInfo) []ImageInfo {
+	var cleaned []ImageInfo
+	for _, block := range blocks {
+		block.URL = cleanImageURL(block.URL)
+
+		i := slices.IndexFunc(cleaned, func(existing ImageInfo) bool { return existing.URL == block.URL })
+		if i < 0 {
+			cleaned = append(cleaned, block)
+			continue
+		}
+		existing := &cleaned[i]
+		if existing.Alt == "" {
+			existing.Alt = block.Alt
+		}
+		if existing.Width == 0 && existing.Height == 0 {
+			existing.Width, existing.Height = block.Width, block.Height
+		}
+		if existing.Type == "" {
+			existing.Type = block.Type
+		}
+	}
+	return detailedImageBlocks(cleaned)
+}
+
+// addImageProperty adds an og:image property to the image blocks. og:image (or og:image:url)
+// starts a new block, and the other properties describe the current one.
+func addImageProperty(blocks []ImageInfo, property, content string) []ImageInfo {
+	content = strings.TrimSpace(content)
+	if property == "og:image" || property == "og:image:url" {
+		// Properties may precede the URL of the first block
+		if len(blocks) > 0 && blocks[len(blocks)-1].URL == "" {
+			blocks[len(blocks)-1].URL = content
+			return blocks
+		}
+		return append(blocks, ImageInfo{URL: content})
+	}
+
+	if len(blocks) == 0 {
+		blocks = append(blocks, ImageInfo{})
+	}
+	current := &blocks[len(blocks)-1]
+
+	switch property {
+	case "og:image:secure_url":
+		if current.URL == "" {
+			current.URL = content
+		}
+	case "og:image:alt":
+		current.Alt = content
+	case "og:image:width":
+		current.Width = max(imageDimension(content), 0)
+	case "og:image:height":
+		current.Height = max(imageDimension(content), 0)
+	case "og:image:type":
+		current.Type = strings.ToLower(content)
+	}
+	return blocks
+}
+
+// detailedImageBlocks returns the image blocks when they carry more than the image URL,
+// that is several images or any sub-property, and nil otherwise
+func detailedImageBlocks(blocks []ImageInfo) []ImageInfo {
+	if len(blocks) > 1 {
+		return blocks
+	}
+	for _, block := range blocks {
+		if block.Alt != "" || block.Width != 0 || block.Height != 0 || block.Type != "" {
+			return blocks
+		}
+	}
+	return nil
+}
+
+// Social cards display images between square and about 2:1, cropping anything outside of that
+const (
+	minCardAspect = 0.9
+	maxCardAspect = 2.1
+)
+
+// maxImageHeaderBytes bounds how much of an image is read to find its dimensions
+const maxImageHeaderBytes = 256 << 10
+
+// checkImageAspect warns when the article image is too tall or too wide for a social card.
+// The dimensions come from og:image:width and og:image:height or, when the page doesn't
+// declare them, from the header of the image itself.
+func checkImageAspect(ctx context.Context, metadata OGMetadata, opts Options) {
+	if metadata.Image == "" {
+		return
+	}
+
+	width, height := 0, 0
+	for _, block := range metadata.ImageObjects {
+		if block.URL == metadata.Image {
+			width, height = block.Width, block.Height
+			break
+		}
+	}
+
+	if (width == 0 || height == 0) && isAbsoluteURL(metadata.Image) {
+		var err error
+		width, height, err = fetchImageSize(ctx, metadata.Image, opts)
+		if err != nil {
+			warnf("could not check the aspect ratio of the image of %s: %v", metadata.Slug, err)
+			return
+		}
+	}
+	if width == 0 || height == 0 {
+		return
+	}
+
+	aspect := float64(width) / float64(height)
+	switch {
+	case aspect < minCardAspect:
+		warnf("the image of %s is unusually tall (%dx%d) and will crop badly on social cards", metadata.Slug, width, height)
+	case aspect > maxCardAspect:
+		warnf("the image of %s is unusually wide (%dx%d) and will crop badly on social cards", metadata.Slug, width, height)
+	}
+}
+
+// verifyImage drops the article image when it can't be fetched, isn't an image, or is smaller
+// than the minimum size, since tiny images are usually placeholders or tracking pixels
+func verifyImage(ctx context.Context, metadata *OGMetadata, opts Options) {
+	if metadata.Image == "" || !isAbsoluteURL(metadata.Image) {
+		return
+	}
+
+	err := checkImageResponse(ctx, metadata.Image, opts)
+	if err != nil {
+		warnf("dropping the image of %s: %v", metadata.Slug, err)
+		metadata.Image = ""
+		metadata.ImageType = ""
+	}
+}
+
+// checkImageResponse fetches the headers of an image and returns an error if it isn't
+// available, isn't an image, or its Content-Length is below -min-image-bytes
+func checkImageResponse(ctx context.Context, imageURL string, opts Options) error {
+	resp, err := fetchPage(ctx, imageURL, opts)
+	if err != nil {
+		return err
+	}
+	// Only the headers are needed
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: status code %d", imageURL, resp.StatusCode)
+	}
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if !strings.HasPrefix(mediaType, "image/") {
+			return fmt.Errorf("%s is not an image (%s)", imageURL, contentType)
+		}
+	}
+
+	// A missing Content-Length (-1) can't be checked
+	if opts.MinImageBytes > 0 && resp.ContentLength >= 0 && resp.ContentLength < int64(opts.MinImageBytes) {
+		return fmt.Errorf("%s is only %d bytes, below -min-image-bytes %d", imageURL, resp.ContentLength, opts.MinImageBytes)
+	}
+	return nil
+}
+
+// fetchImageSize reads the dimensions of a JPEG, PNG or GIF image from its header,
+// without downloading the whole image
+func fetchImageSize(ctx context.Context, imageURL string, opts Options) (int, int, error) {
+	resp, err := fetchPage(ctx, imageURL, opts)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, 0, fmt.Errorf("status code %d", resp.StatusCode)
+	}
+
+	config, _, err := image.DecodeConfig(io.LimitReader(resp.Body, maxImageHeaderBytes))
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read image size: %w", err)
+	}
+	return config.Width, config.Height, nil
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDataURIImage(t *testing.T) {
+	server := newFixtureServer(t)
+	url := server.handle("/blog/pixel", fixture{File: "data-image.html"})
+
+	metadata := extractPage(t, url, Options{})
+	if metadata.Image != "" {
+		t.Errorf("image = %q, want the data: URI dropped", metadata.Image)
+	}
+
+	imageDir := t.TempDir()
+	metadata = extractPage(t, url, Options{SaveDataImages: true, ImageDir: imageDir})
+	if want := filepath.Join(imageDir, "pixel.png"); metadata.Image != want {
+		t.Fatalf("image = %q, want %q", metadata.Image, want)
+	}
+	data, err := os.ReadFile(metadata.Image)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")) {
+		t.Errorf("the saved image isn't the decoded PNG: % x", data[:min(len(data), 8)])
+	}
+}
+
+func TestDataURIImageType(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		page     string
+		wantType string
+		wantFile string
+	}{
+		// Without a media type in the URI, the extension comes from og:image:type
+		{"data-image-typed.html", "image/webp", "post.webp"},
+		// The media type of the URI wins over a declared type
+		{"data-image-declared.html", "image/jpeg", "post.png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			url := server.handle("/blog/"+tt.page+"/post", fixture{File: tt.page})
+			imageDir := t.TempDir()
+
+			metadata := extractPage(t, url, Options{SaveDataImages: true, ImageDir: imageDir})
+			if metadata.ImageType != tt.wantType {
+				t.Errorf("imageType = %q, want %q", metadata.ImageType, tt.wantType)
+			}
+			if want := filepath.Join(imageDir, tt.wantFile); metadata.Image != want {
+				t.Errorf("image = %q, want %q", metadata.Image, want)
+			}
+		})
+	}
+}
+
+func TestImageBlocks(t *testing.T) {
+	server := newFixtureServer(t)
+
+	metadata := extractPage(t, server.page("image-blocks.html"), Options{})
+	want := []ImageInfo{
+		{URL: "https://blog.example/images/flamegraph-wide.png", Alt: "A flame graph of the API server", Width: 1200, Height: 630, Type: "image/png"},
+		{URL: "https://blog.example/images/flamegraph-square.jpg", Alt: "The same flame graph, cropped", Width: 600, Height: 600, Type: "image/jpeg"},
+	}
+	if !slices.Equal(metadata.ImageObjects, want) {
+		t.Errorf("imageObjects = %+v, want %+v", metadata.ImageObjects, want)
+	}
+	// The first block is the primary image
+	if metadata.Image != want[0].URL || metadata.ImageType != want[0].Type {
+		t.Errorf("image = %q (%q), want the first block", metadata.Image, metadata.ImageType)
+	}
+}
+
+func TestRewriteImageURL(t *testing.T) {
+	rules := mappingFlag{}
+	rules.Set("https://blog.example/images/=https://cdn.example/blog/")
+	rules.Set("https://blog.example/=https://static.example/")
+
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"https://blog.example/images/vibe.png", "https://cdn.example/blog/vibe.png"},
+		{"https://blog.example/assets/logo.png", "https://static.example/assets/logo.png"},
+		{"https://other.example/images/vibe.png", "https://other.example/images/vibe.png"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := rewriteImageURL(tt.image, rules); got != tt.want {
+			t.Errorf("rewriteImageURL(%q) = %q, want %q", tt.image, got, tt.want)
+		}
+	}
+
+	// The rules apply to the extracted image
+	server := newFixtureServer(t)
+	metadata := extractPage(t, server.page("article.html"), Options{ImageRewrites: rules})
+	if metadata.Image != "https://cdn.example/blog/vibe.png" {
+		t.Errorf("image = %q, want it rewritten to the CDN", metadata.Image)
+	}
+}
+
+func TestImageAspect(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		declared      bool
+		want          string
+	}{
+		{"tall from the image header", 100, 500, false, "unusually tall (100x500)"},
+		{"wide from og:image:width", 1000, 200, true, "unusually wide (1000x200)"},
+		{"card from the image header", 1200, 630, false, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var cover bytes.Buffer
+			if err := png.Encode(&cover, image.NewGray(image.Rect(0, 0, test.width, test.height))); err != nil {
+				t.Fatal(err)
+			}
+
+			server := newFixtureServer(t)
+			imageURL := server.handle("/images/cover.png", fixture{
+				Body:   cover.String(),
+				Header: http.Header{"Content-Type": {"image/png"}},
+			})
+			page := `<html><head><meta property="og:title" content="Cover"><meta property="og:image" content="` + imageURL + `">`
+			if test.declared {
+				page += fmt.Sprintf(`<meta property="og:image:width" content="%d"><meta property="og:image:height" content="%d">`, test.width, test.height)
+			}
+			url := server.handle("/blog/cover", fixture{Body: page + "</head></html>"})
+
+			result := runCLI(t, "-quiet", "-validate-image-aspect", url, writeArticles(t, "articles.json"))
+			if result.code != 0 {
+				t.Fatalf("exit code %d, stderr: %s", result.code, result.stderr)
+			}
+			if test.want == "" {
+				if strings.Contains(result.stderr, "crop badly") {
+					t.Errorf("unexpected aspect warning: %s", result.stderr)
+				}
+			} else if !strings.Contains(result.stderr, test.want) {
+				t.Errorf("stderr = %q, want a warning containing %q", result.stderr, test.want)
+			}
+			if got := len(server.requestsTo("/images/cover.png")); test.declared && got != 0 {
+				t.Errorf("the image was fetched %d times although its dimensions are declared", got)
+			}
+		})
+	}
+}
+
+func TestMinImageBytes(t *testing.T) {
+	server := newFixtureServer(t)
+	pngHeader := http.Header{"Content-Type": {"image/png"}}
+	pixel := server.handle("/images/pixel.png", fixture{Body: strings.Repeat("x", 43), Header: pngHeader})
+	cover := server.handle("/images/cover.png", fixture{Body: strings.Repeat("x", 2048), Header: pngHeader})
+
+	tests := []struct {
+		name     string
+		image    string
+		minBytes int
+		wantErr  bool
+	}{
+		{"tiny image below the threshold", pixel, 1024, true},
+		{"cover above the threshold", cover, 1024, false},
+		{"tiny image without a threshold", pixel, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkImageResponse(context.Background(), tt.image, Options{MinImageBytes: tt.minBytes})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "below -min-image-bytes") {
+					t.Errorf("err = %v, want the image flagged as too small", err)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			metadata := OGMetadata{Slug: "post", Image: tt.image, ImageType: "image/png"}
+			verifyImage(context.Background(), &metadata, Options{MinImageBytes: tt.minBytes})
+			if dropped := metadata.Image == ""; dropped != tt.wantErr {
+				t.Errorf("image = %q, want it dropped: %v", metadata.Image, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCleanImageURL(t *testing.T) {
+	tests := []struct {
+		image string
+		want  string
+	}{
+		{"https://cdn.example/cover.jpg?w=1200&q=80", "https://cdn.example/cover.jpg"},
+		{"https://cdn.example/cover.jpg?W=1200&version=3&fit=crop&id=7", "https://cdn.example/cover.jpg?version=3&id=7"},
+		{"https://cdn.example/cover.jpg?id=7&height=630#hero", "https://cdn.example/cover.jpg?id=7#hero"},
+		{"https://imgix.example/cover.jpg?w=1200&s=0a1b2c", "https://imgix.example/cover.jpg?w=1200&s=0a1b2c"},
+		{"https://cdn.example/cover.jpg?v=2", "https://cdn.example/cover.jpg?v=2"},
+		{"https://cdn.example/cover.jpg", "https://cdn.example/cover.jpg"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := cleanImageURL(tt.image); got != tt.want {
+			t.Errorf("cleanImageURL(%q) = %q, want %q", tt.image, got, tt.want)
+		}
+	}
+
+	// Sizes of one image listed as separate blocks become one
+	blocks := cleanImageBlocks([]ImageInfo{
+		{URL: "https://cdn.example/cover.jpg?w=1200", Width: 1200, Height: 630},
+		{URL: "https://cdn.example/cover.jpg?w=600", Alt: "The cover"},
+		{URL: "https://cdn.example/other.jpg?w=600&id=2"},
+	})
+	want := []ImageInfo{
+		{URL: "https://cdn.example/cover.jpg", Alt: "The cover", Width: 1200, Height: 630},
+		{URL: "https://cdn.example/other.jpg?id=2"},
+	}
+	if !slices.Equal(blocks, want) {
+		t.Errorf("blocks = %+v, want %+v", blocks, want)
+	}
+}
+
+func TestContentImageFallback(t *testing.T) {
+	server := newFixtureServer(t)
+	url := server.page("content-images.html")
+
+	// The icons, the tracking pixel and the spacer are skipped for the lazy-loaded content image
+	metadata := extractPage(t, url, Options{ImgFallback: true, ImgMinSize: 200})
+	if want := server.URL + "/images/flamegraph.png"; metadata.Image != want {
+		t.Errorf("image = %q, want %q", metadata.Image, want)
+	}
+
+	metadata = extractPage(t, url, Options{ImgMinSize: 200})
+	if metadata.Image != "" {
+		t.Errorf("image = %q without -img-fallback, want none", metadata.Image)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Results of a URL in the import log
+const (
+	importImported = "imported"
+	importSkipped  = "skipped"
+	importFailed   = "failed"
+)
+
+// ImportLogRecord is a line of the import log, describing what happened to a URL of a run
+type ImportLogRecord struct {
+	Timestamp     string   `json:"timestamp"`
+	URL           string   `json:"url"`
+	Result        string   `json:"result"`
+	Articles      int      `json:"articles"`
+	FieldsMissing []string `json:"fieldsMissing,omitempty"`
+	Reason        string   `json:"reason,omitempty"`
+}
+
+// newImportLogRecord describes the outcome of a URL: the articles written from it, with the
+// fields missing from any of them, and the error or skip reason, if any
+func newImportLogRecord(url, result string, articles []OGMetadata, reason string) ImportLogRecord {
+	record := ImportLogRecord{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		URL:       url,
+		Result:    result,
+		Articles:  len(articles),
+		Reason:    reason,
+	}
+
+	seen := map[string]bool{}
+	for _, article := range articles {
+		for _, field := range missingFields(article) {
+			if !seen[field] {
+				seen[field] = true
+				record.FieldsMissing = append(record.FieldsMissing, field)
+			}
+		}
+	}
+
+	return record
+}
+
+// appendImportLog appends the record as a line of the newline-delimited JSON log file,
+// creating it if needed. The file is only appended to, so it builds up across runs.
+func appendImportLog(filePath string, record ImportLogRecord, mode os.FileMode) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	perm := mode
+	if perm == 0 {
+		perm = 0644
+	}
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, perm)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// jsonAPIFields lists the keys each field is read from in a JSON API response, in order of preference
+var jsonAPIFields = struct {
+	title, description, image, date, author []string
+}{
+	title:       []string{"title", "headline", "name"},
+	description: []string{"description", "summary", "excerpt", "subtitle"},
+	image:       []string{"image", "imageUrl", "image_url", "featured_image", "jetpack_featured_media_url", "thumbnail", "cover_image"},
+	date:        []string{"datePublished", "publishDate", "published_at", "publishedAt", "date", "created_at", "createdAt"},
+	author:      []string{"author", "authors", "byline"},
+}
+
+// jsonAPIWrappers are keys under which APIs commonly nest the actual article object
+var jsonAPIWrappers = []string{"data", "article", "post", "result"}
+
+// isJSONResponse reports whether the response content type is JSON
+func isJSONResponse(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// extractJSONAPIArticle maps the common fields of a JSON API response describing an article
+func extractJSONAPIArticle(body []byte, url string, opts Options) (OGMetadata, error) {
+	var data interface{}
+	err := json.Unmarshal(body, &data)
+	if err != nil {
+		return OGMetadata{}, fmt.Errorf("invalid JSON API response: %w", err)
+	}
+
+	// A list holds the article as its first element
+	if list, ok := data.([]interface{}); ok && len(list) > 0 {
+		data = list[0]
+	}
+	object, ok := data.(map[string]interface{})
+	if !ok {
+		return OGMetadata{}, fmt.Errorf("JSON API response is not an object")
+	}
+	for _, key := range jsonAPIWrappers {
+		if inner, ok := object[key].(map[string]interface{}); ok {
+			object = inner
+			break
+		}
+	}
+
+	return jsonAPIObjectArticle(object, url, opts)
+}
+
+// jsonAPIObjectArticle maps the common fields of a JSON object describing the article at the URL
+func jsonAPIObjectArticle(object map[string]interface{}, url string, opts Options) (OGMetadata, error) {
+	var err error
+	metadata := OGMetadata{
+		URL:         url,
+		Title:       jsonAPIString(object, jsonAPIFields.title),
+		Description: jsonAPIString(object, jsonAPIFields.description),
+		PublishDate: jsonAPIString(object, jsonAPIFields.date),
+	}
+	for _, key := range jsonAPIFields.image {
+		if image := imageFromJSONValue(object[key]); image != "" {
+			metadata.Image = resolveURL(url, image)
+			break
+		}
+	}
+	for _, key := range jsonAPIFields.author {
+		if author := authorFromJSONValue(object[key]); author != "" {
+			metadata.Author = author
+			break
+		}
+	}
+
+	metadata.Slug, err = slugFor(url, opts)
+	if err != nil {
+		return metadata, err
+	}
+
+	applyOutputOptions(&metadata, opts)
+	return metadata, nil
+}
+
+// jsonAPIString returns the first non-empty string among the keys. Values may also be objects
+// holding the text under "rendered", as in the WordPress REST API.
+func jsonAPIString(object map[string]interface{}, keys []string) string {
+	for _, key := range keys {
+		switch value := object[key].(type) {
+		case string:
+			if strings.TrimSpace(value) != "" {
+				return strings.TrimSpace(value)
+			}
+		case map[string]interface{}:
+			if rendered, ok := value["rendered"].(string); ok && strings.TrimSpace(rendered) != "" {
+				return strings.TrimSpace(rendered)
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONAPIArticle(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		file string
+		want OGMetadata
+	}{
+		{"api-post.json", OGMetadata{
+			Title:       "Profiling Go Services",
+			Description: "Finding the hot paths of a Go service.",
+			Image:       server.URL + "/media/profiling.png",
+			PublishDate: "2024-02-10T08:00:00Z",
+			Author:      "Ada Example",
+		}},
+		{"api-wordpress.json", OGMetadata{
+			Title:       "Building a Blog with Vibe Coding",
+			Description: "How we built our blog.",
+			Image:       "https://blog.example/images/vibe.png",
+			PublishDate: "2024-03-15T09:30:00",
+			Author:      "Ada Example",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			url := server.handle("/posts/"+strings.TrimSuffix(tt.file, ".json"), fixture{File: tt.file})
+			metadata := extractPage(t, url, Options{PreferJSONAPI: true})
+
+			tt.want.URL = url
+			tt.want.Slug = strings.TrimSuffix(tt.file, ".json")
+			if !reflect.DeepEqual(metadata, tt.want) {
+				t.Errorf("metadata = %+v, want %+v", metadata, tt.want)
+			}
+
+			accept := server.requestsTo("/posts/" + tt.want.Slug)[0].Header.Get("Accept")
+			if !strings.HasPrefix(accept, "application/json") {
+				t.Errorf("Accept = %q, want JSON first", accept)
+			}
+		})
+	}
+}
+
+func TestResponseSizeLimit(t *testing.T) {
+	server := newFixtureServer(t)
+	padding := strings.Repeat(" ", maxResponseBytes)
+	jsonHeader := http.Header{"Content-Type": {"application/json"}}
+
+	htmlURL := server.handle("/huge.html", fixture{
+		Body: `<html><head><meta property="og:title" content="Huge Page"></head><body>` + padding + `</body></html>`,
+	})
+	metadata := extractPage(t, htmlURL, Options{})
+	if metadata.Title != "Huge Page" {
+		t.Errorf("title of a huge page = %q, want %q", metadata.Title, "Huge Page")
+	}
+
+	apiURL := server.handle("/huge.json", fixture{Body: `{"title": "Huge"` + padding + `}`, Header: jsonHeader})
+	_, err := extractArticles(context.Background(), apiURL, Options{Quiet: true, PreferJSONAPI: true})
+	if err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("huge JSON API response: err = %v, want a size error", err)
+	}
+
+	server.handle("/index/1", fixture{Body: `[` + padding + `]`, Header: jsonHeader})
+	_, err = extractAPIIndex(context.Background(), server.URL+"/index/{page}", Options{Quiet: true})
+	if err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("huge API index page: err = %v, want a size error", err)
+	}
+}
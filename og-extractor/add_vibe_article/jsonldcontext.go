@@ -0,0 +1,138 @@
+package main
+
+import (
+	"strings"
+)
+
+// schemaOrgIRIs are the forms of the schema.org vocabulary IRI that pages expand terms with
+var schemaOrgIRIs = []string{"https://schema.org/", "http://schema.org/", "https://www.schema.org/", "http://www.schema.org/"}
+
+// isSchemaOrgIRI reports whether an IRI is the schema.org vocabulary or a document under it,
+// whatever its scheme, trailing slash or www. prefix
+func isSchemaOrgIRI(iri string) bool {
+	iri = strings.ToLower(strings.TrimSpace(iri))
+	iri = strings.TrimPrefix(strings.TrimPrefix(iri, "https://"), "http://")
+	iri = strings.TrimPrefix(iri, "www.")
+	return iri == "schema.org" || strings.HasPrefix(iri, "schema.org/")
+}
+
+// isSchemaOrgContext reports whether a JSON-LD "@context" refers to schema.org: a string such as
+// "http://schema.org", an object mapping "@vocab" or a prefix to schema.org, or an array holding either
+func isSchemaOrgContext(context interface{}) bool {
+	switch c := context.(type) {
+	case string:
+		return isSchemaOrgIRI(c)
+	case map[string]interface{}:
+		for _, value := range c {
+			if iri, ok := value.(string); ok && isSchemaOrgIRI(iri) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range c {
+			if isSchemaOrgContext(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// schemaOrgPrefixes adds the prefixes an object "@context" maps to schema.org, such as "s" in
+// {"s": "http://schema.org/"}, to the ones already in scope
+func schemaOrgPrefixes(context interface{}, inScope map[string]bool) map[string]bool {
+	prefixes := map[string]bool{}
+	for prefix := range inScope {
+		prefixes[prefix] = true
+	}
+
+	var collect func(interface{})
+	collect = func(context interface{}) {
+		switch c := context.(type) {
+		case map[string]interface{}:
+			for key, value := range c {
+				if iri, ok := value.(string); ok && !strings.HasPrefix(key, "@") && isSchemaOrgIRI(iri) {
+					prefixes[key] = true
+				}
+			}
+		case []interface{}:
+			for _, item := range c {
+				collect(item)
+			}
+		}
+	}
+	collect(context)
+	return prefixes
+}
+
+// schemaOrgTerm returns a term, a property name or a type, without the schema.org IRI or prefix
+// it may be written with, so "http://schema.org/Article" and "schema:Article" both give "Article"
+func schemaOrgTerm(term string, prefixes map[string]bool) string {
+	for _, iri := range schemaOrgIRIs {
+		if strings.HasPrefix(term, iri) {
+			return strings.TrimPrefix(term, iri)
+		}
+	}
+	if prefix, name, found := strings.Cut(term, ":"); found && (prefix == "schema" || prefixes[prefix]) {
+		return name
+	}
+	return term
+}
+
+// normalizeJSONLD rewrites the properties and types of JSON-LD data written with schema.org IRIs
+// or prefixes to their plain names, so the extraction doesn't depend on the form of "@context".
+// Objects whose own "@context" isn't schema.org describe something else and are dropped.
+func normalizeJSONLD(value interface{}, prefixes map[string]bool) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		items := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if item = normalizeJSONLD(item, prefixes); item != nil {
+				items = append(items, item)
+			}
+		}
+		return items
+	case map[string]interface{}:
+		if context, ok := v["@context"]; ok {
+			if !isSchemaOrgContext(context) {
+				return nil
+			}
+			prefixes = schemaOrgPrefixes(context, prefixes)
+		}
+
+		node := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			name := schemaOrgTerm(key, prefixes)
+			if _, plain := v[name]; plain && name != key {
+				// The plain property wins over the same one written with a prefix
+				continue
+			}
+			if key == "@type" {
+				node[name] = normalizeJSONLDType(item, prefixes)
+				continue
+			}
+			node[name] = normalizeJSONLD(item, prefixes)
+		}
+		return node
+	}
+	return value
+}
+
+// normalizeJSONLDType returns a "@type" value, a string or an array, with plain type names
+func normalizeJSONLDType(value interface{}, prefixes map[string]bool) interface{} {
+	switch t := value.(type) {
+	case string:
+		return schemaOrgTerm(t, prefixes)
+	case []interface{}:
+		types := make([]interface{}, len(t))
+		for i, item := range t {
+			if name, ok := item.(string); ok {
+				types[i] = schemaOrgTerm(name, prefixes)
+			} else {
+				types[i] = item
+			}
+		}
+		return types
+	}
+	return value
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestJSONLDContextForms(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		page       string
+		wantDate   string
+		wantAuthor string
+	}{
+		{"context-http.html", "2023-05-15", "Ada Example"},
+		{"context-https-slash.html", "2023-05-15", "Ada Example"},
+		{"context-vocab.html", "2023-05-15", "Ada Example"},
+		{"context-prefix.html", "2023-05-15", "Ada Example"},
+		{"context-array.html", "2023-05-15", "Ada Example"},
+		{"context-foreign.html", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			metadata := extractPage(t, server.page(tt.page), Options{NoURLDate: true})
+			if metadata.PublishDate != tt.wantDate {
+				t.Errorf("publishDate = %q, want %q", metadata.PublishDate, tt.wantDate)
+			}
+			if metadata.Author != tt.wantAuthor {
+				t.Errorf("author = %q, want %q", metadata.Author, tt.wantAuthor)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	neturl "net/url"
+	"strings"
+
+	"add_vibe_article/articlesjson"
+
+	"golang.org/x/net/html"
+)
+
+// linkRelations returns the relations and the target of a <link> element
+func linkRelations(n *html.Node) (rels []string, href string) {
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "rel":
+			rels = strings.Fields(strings.ToLower(attr.Val))
+		case "href":
+			href = strings.TrimSpace(attr.Val)
+		}
+	}
+	return rels, href
+}
+
+// parseLinkHeaders maps each relation of the HTTP Link headers, such as
+// `<https://example.com/post>; rel="canonical"`, to its target. The first target of a relation wins.
+func parseLinkHeaders(headers []string) map[string]string {
+	links := map[string]string{}
+	for _, header := range headers {
+		for _, link := range splitLinkHeader(header) {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			target = strings.TrimSpace(target[1 : len(target)-1])
+
+			for _, param := range parts[1:] {
+				name, value, found := strings.Cut(param, "=")
+				if !found || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.ToLower(strings.Trim(strings.TrimSpace(value), `"`))) {
+					if _, ok := links[rel]; !ok {
+						links[rel] = target
+					}
+				}
+			}
+		}
+	}
+	return links
+}
+
+// splitLinkHeader splits a Link header into its comma-separated links, ignoring commas inside <...>
+func splitLinkHeader(header string) []string {
+	var links []string
+	inTarget := false
+	start := 0
+	for i, c := range header {
+		switch c {
+		case '<':
+			inTarget = true
+		case '>':
+			inTarget = false
+		case ',':
+			if !inTarget {
+				links = append(links, header[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(links, header[start:])
+}
+
+// checkCanonicalHosts keeps syndicated copies from taking the URL of an aggregator: with
+// -canonical-host, an og:url on a host that isn't allowed is replaced by the fetched URL, and
+// such a canonical link is dropped. The fetched host and subdomains of allowed hosts are allowed.
+func checkCanonicalHosts(metadata *OGMetadata, pageURL string, opts Options) {
+	if len(opts.CanonicalHosts) == 0 {
+		return
+	}
+
+	if isAbsoluteURL(metadata.URL) && !isCanonicalHost(metadata.URL, pageURL, opts.CanonicalHosts) {
+		warnf("og:url %s is not on an allowed canonical host, keeping %s", metadata.URL, pageURL)
+		metadata.URL = pageURL
+	}
+	if isAbsoluteURL(metadata.CanonicalURL) && !isCanonicalHost(metadata.CanonicalURL, pageURL, opts.CanonicalHosts) {
+		warnf("ignoring canonical link %s, which is not on an allowed canonical host", metadata.CanonicalURL)
+		metadata.CanonicalURL = ""
+	}
+}
+
+// isCanonicalHost reports whether the URL is on the host of the fetched page or on one of the
+// allowed hosts or their subdomains, ignoring case and a leading "www."
+func isCanonicalHost(link, pageURL string, allowed []string) bool {
+	if articlesjson.SameHost(link, pageURL) {
+		return true
+	}
+
+	parsed, err := neturl.Parse(link)
+	if err != nil {
+		return false
+	}
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	for _, allowedHost := range allowed {
+		allowedHost = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(allowedHost)), "www.")
+		if host == allowedHost || strings.HasSuffix(host, "."+allowedHost) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyLinkHeaders uses the canonical, author and pagination links of the response as fallbacks
+// for the ones the page doesn't declare
+func applyLinkHeaders(metadata *OGMetadata, headers []string, pageURL string) {
+	links := parseLinkHeaders(headers)
+	if metadata.CanonicalURL == "" && links["canonical"] != "" {
+		metadata.CanonicalURL = resolveURL(pageURL, links["canonical"])
+	}
+	if metadata.AuthorURL == "" && links["author"] != "" {
+		metadata.AuthorURL = resolveURL(pageURL, links["author"])
+	}
+	if metadata.NextURL == "" && links["next"] != "" {
+		metadata.NextURL = resolveURL(pageURL, links["next"])
+	}
+	prev := links["prev"]
+	if prev == "" {
+		prev = links["previous"]
+	}
+	if metadata.PrevURL == "" && prev != "" {
+		metadata.PrevURL = resolveURL(pageURL, prev)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestLinkHeaders(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		name string
+		file string
+		link string
+		want string
+	}{
+		{"absolute", "article.html", `<https://blog.example/posts/canonical>; rel="canonical"`, "https://blog.example/posts/canonical"},
+		{"relative", "article.html", `</posts/canonical>; rel=canonical`, server.URL + "/posts/canonical"},
+		{"among-other-links", "article.html", `<https://cdn.example/a,b.css>; rel=preload, <https://blog.example/posts/canonical>; rel="canonical"`, "https://blog.example/posts/canonical"},
+		{"page-link-preferred", "canonical-link.html", `<https://blog.example/posts/canonical>; rel="canonical"`, "https://blog.example/posts/from-the-page"},
+		{"no-link", "article.html", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.link != "" {
+				header.Set("Link", tt.link)
+			}
+			url := server.handle("/blog/"+tt.name, fixture{File: tt.file, Header: header})
+
+			metadata := extractPage(t, url, Options{})
+			if metadata.CanonicalURL != tt.want {
+				t.Errorf("canonicalUrl = %q, want %q", metadata.CanonicalURL, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanonicalHostAllowlist(t *testing.T) {
+	server := newFixtureServer(t)
+	url := server.handle("/blog/syndicated", fixture{Body: `<html><head>
+<meta property="og:title" content="Building a Blog with Vibe Coding">
+<meta property="og:url" content="https://medium.example/@ada/building-a-blog-1a2b">
+<link rel="canonical" href="https://medium.example/@ada/building-a-blog-1a2b">
+</head></html>`})
+
+	aggregator := "https://medium.example/@ada/building-a-blog-1a2b"
+	tests := []struct {
+		name          string
+		hosts         []string
+		wantURL       string
+		wantCanonical string
+	}{
+		{"no allowlist", nil, aggregator, aggregator},
+		{"off the allowlist", []string{"blog.example"}, url, ""},
+		{"on the allowlist", []string{"blog.example", "MEDIUM.example"}, aggregator, aggregator},
+		{"parent host on the allowlist", []string{"example"}, aggregator, aggregator},
+	}
+	for _, tt := range tests {
+		metadata := extractPage(t, url, Options{CanonicalHosts: tt.hosts})
+		if metadata.URL != tt.wantURL {
+			t.Errorf("%s: url = %q, want %q", tt.name, metadata.URL, tt.wantURL)
+		}
+		if metadata.CanonicalURL != tt.wantCanonical {
+			t.Errorf("%s: canonicalUrl = %q, want %q", tt.name, metadata.CanonicalURL, tt.wantCanonical)
+		}
+	}
+}
+
+func TestPaginationLinks(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		name     string
+		file     string
+		link     string
+		wantNext string
+		wantPrev string
+	}{
+		{"page links", "pagination.html", "", server.URL + "/blog/series/part-3?ref=next", server.URL + "/blog/series/part-1"},
+		{"link header", "article.html", `<part-3>; rel="next", <../series/part-1>; rel="previous"`, server.URL + "/blog/series/part-3", server.URL + "/blog/series/part-1"},
+		{"page links preferred", "pagination.html", `<https://blog.example/other>; rel="next"`, server.URL + "/blog/series/part-3?ref=next", server.URL + "/blog/series/part-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.link != "" {
+				header.Set("Link", tt.link)
+			}
+			server.handle("/blog/series/part-2", fixture{File: tt.file, Header: header})
+
+			metadata := extractPage(t, server.URL+"/blog/series/part-2", Options{})
+			if metadata.NextURL != tt.wantNext || metadata.PrevURL != tt.wantPrev {
+				t.Errorf("next, prev = %q, %q, want %q, %q", metadata.NextURL, metadata.PrevURL, tt.wantNext, tt.wantPrev)
+			}
+		})
+	}
+}
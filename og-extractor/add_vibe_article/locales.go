@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// extractLocalizedText returns the title and description of the page in each language given
+// with -lang, keyed by language. The metadata already extracted is used for the first one, and
+// the page is fetched again with the Accept-Language of each of the others, for sites that
+// negotiate the language of their OG tags. Languages that fail are skipped with a warning.
+func extractLocalizedText(ctx context.Context, url string, metadata OGMetadata, opts Options) map[string]LocalizedText {
+	localized := map[string]LocalizedText{
+		opts.Langs[0]: {Title: metadata.Title, Description: metadata.Description},
+	}
+
+	for _, lang := range opts.Langs[1:] {
+		langOpts := opts
+		langOpts.Lang = lang
+
+		text, err := fetchLocalizedText(ctx, url, langOpts)
+		if err != nil {
+			warnf("failed to fetch %s in %s: %v", url, lang, err)
+			continue
+		}
+		localized[lang] = text
+	}
+
+	return localized
+}
+
+// fetchLocalizedText fetches the page in the language of the options and extracts its title and
+// description. Only those are read from the page, so the other steps of the extraction, such as
+// saving data: images or warning about the page, aren't repeated for every language.
+func fetchLocalizedText(ctx context.Context, url string, opts Options) (LocalizedText, error) {
+	resp, err := fetchPage(ctx, url, opts)
+	if err != nil {
+		return LocalizedText{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return LocalizedText{}, fmt.Errorf("status code %d", resp.StatusCode)
+	}
+
+	// The title and description are in the head, so the start of a huge page is enough
+	body, _, err := readResponse(resp.Body)
+	if err != nil {
+		return LocalizedText{}, err
+	}
+
+	page := decodePage(body, resp.Header.Get("Content-Type"), url, opts)
+	if isXHTML(resp.Header.Get("Content-Type")) {
+		page = expandSelfClosingTags(page)
+	}
+	doc, err := html.Parse(bytes.NewReader(page))
+	if err != nil {
+		return LocalizedText{}, err
+	}
+
+	metadata := localizedMetadata(doc)
+	applyTextOptions(&metadata, opts)
+	return LocalizedText{Title: metadata.Title, Description: metadata.Description}, nil
+}
+
+// localizedMetadata reads the title, description and site name of a page from its Open Graph
+// tags, falling back to the <title> element for the title like the full extraction does
+func localizedMetadata(doc *html.Node) OGMetadata {
+	var metadata OGMetadata
+
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		if n.Type == html.ElementNode && localName(n.Data) == "meta" {
+			var property, content, value string
+			for _, attr := range n.Attr {
+				switch localName(attr.Key) {
+				case "property", "name":
+					property = attr.Val
+				case "content":
+					content = attr.Val
+				case "value":
+					value = attr.Val
+				}
+			}
+			if strings.TrimSpace(content) == "" {
+				content = value
+			}
+
+			switch {
+			case property == "og:title" && metadata.Title == "":
+				metadata.Title = content
+			case property == "og:description" && metadata.Description == "":
+				metadata.Description = content
+			case property == "og:site_name" && metadata.Source == "":
+				metadata.Source = content
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			visit(c)
+		}
+	}
+	visit(doc)
+
+	if metadata.Title == "" {
+		metadata.Title = pageTitle(doc)
+	}
+	return metadata
+}
+
+// normalizeLocale writes a language tag the BCP 47 way, so the en_US of og:locale and the en-US
+// of HTML and HTTP headers are stored alike
+func normalizeLocale(locale string) string {
+	return strings.ReplaceAll(strings.TrimSpace(locale), "_", "-")
+}
+
+// contentLanguage returns the first language of a Content-Language header, such as "de-DE"
+// for "de-DE, en", or an empty string
+func contentLanguage(header string) string {
+	first, _, _ := strings.Cut(header, ",")
+	return normalizeLocale(first)
+}
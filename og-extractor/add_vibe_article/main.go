@@ -1,230 +1,2311 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
+	neturl "net/url"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+	"unicode"
+
+	"add_vibe_article/articlesjson"
 
 	"golang.org/x/net/html"
+	"golang.org/x/net/publicsuffix"
+)
+
+// The articles and the collection written to the JSON file are those of the articlesjson package
+type (
+	OGMetadata         = articlesjson.OGMetadata
+	ImageInfo          = articlesjson.ImageInfo
+	LocalizedText      = articlesjson.LocalizedText
+	ExtractionMeta     = articlesjson.ExtractionMeta
+	FieldConfidence    = articlesjson.FieldConfidence
+	ArticlesCollection = articlesjson.ArticlesCollection
 )
 
-// OGMetadata struct to store Open Graph metadata
-type OGMetadata struct {
-	URL         string `json:"url"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	Image       string `json:"image"`
-	Slug        string `json:"slug"`
-	PublishDate string `json:"publishDate,omitempty"`
-	Source      string `json:"source,omitempty"`
+// Options holds the settings provided through command line flags
+type Options struct {
+	Limit               int
+	ArchivePath         string
+	Lang                string
+	Langs               stringListFlag
+	IncludeMeta         bool
+	IncludeConfidence   bool
+	FrameworkData       bool
+	JSONLDOnly          bool
+	StripHTML           bool
+	MetaMapping         mappingFlag
+	TrustFetchedURL     bool
+	AppendArrayOnly     bool
+	FetchTimeout        time.Duration
+	TotalTimeout        time.Duration
+	TLSMinVersion       tlsVersionFlag
+	TLSCiphers          cipherSuitesFlag
+	Transport           http.RoundTripper
+	Format              string
+	OutputByCategory    bool
+	DBPath              string
+	FuzzyDedup          bool
+	FuzzyThreshold      float64
+	DatesFile           string
+	RequiredFieldsFile  string
+	RequiredFields      *RequiredFieldsPolicy
+	DateOverrides       map[string]string
+	SelfTest            bool
+	SaveDataImages      bool
+	ImageDir            string
+	SlugSegment         int
+	OutputMode          fileModeFlag
+	Indent              indentFlag
+	ImgFallback         bool
+	ImgMinSize          int
+	ReportMissing       bool
+	Quiet               bool
+	DedupeAgainst       string
+	DefaultTZ           locationFlag
+	MaxPerHost          int
+	SaveHTMLDir         string
+	Update              bool
+	MergeTags           bool
+	MergeByTitle        bool
+	ChecksumFile        bool
+	VerifyChecksum      bool
+	ImageRewrites       mappingFlag
+	CleanImageURL       bool
+	SkipPaywalled       bool
+	NormalizeWhitespace bool
+	Review              bool
+	Exclude             regexpListFlag
+	FileFormat          string
+	CanonicalizeSlugs   bool
+	CompareWithLive     bool
+	Apply               bool
+	GroupBySource       bool
+	Strict              bool
+	PreferCanonicalSlug bool
+	DisambiguateSlugs   bool
+	KeepQueryInSlug     bool
+	FlattenSlugSpaces   bool
+	MaxSlugLength       int
+	CleanURL            bool
+	NoURLDate           bool
+	ViaArchive          bool
+	LogPath             string
+	ConvertLayout       string
+	PreferJSONAPI       bool
+	APIIndex            string
+	APIMaxPages         int
+	FoldURLCase         bool
+	CountOnly           bool
+	SortFile            bool
+	SortChunkSize       int
+	PrintSchema         bool
+	Verbose             bool
+	Mode                string
+	Clipboard           bool
+	FailuresPath        string
+	StripSiteSuffix     bool
+	MaxDescLength       int
+	FailFast            bool
+	ValidateImageAspect bool
+	VerifyImage         bool
+	MinImageBytes       int
+	RetryDifferentUA    bool
+	RetryBudget         int
+	AlternateUAs        stringListFlag
+	CanonicalHosts      stringListFlag
+	UserAgent           string
+	Accept              string
+	TwoPass             bool
+	ManifestPath        string
+	URLOverrides        map[string]ArticleOverrides
+	MasterIndex         *articleIndex
+	ReviewState         *reviewState
+}
+
+func main() {
+	opts := parseFlags()
+
+	// The self-test doesn't need any arguments
+	if opts.SelfTest {
+		err := runSelfTest(opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Self-test failed: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println("Self-test passed")
+		return
+	}
+
+	// The schema doesn't need any arguments either
+	if opts.PrintSchema {
+		schema, err := marshalSchema()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error generating the schema: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(schema))
+		return
+	}
+
+	// Counting only reads the JSON file
+	if opts.CountOnly {
+		if flag.NArg() != 1 {
+			printUsage()
+			os.Exit(1)
+		}
+		count, err := countArticles(flag.Arg(0))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error counting articles: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(count)
+		return
+	}
+
+	// A crash during a previous run may have left a temporary file next to the file a
+	// maintenance pass rewrites
+	if (opts.ConvertLayout != "" || opts.SortFile || opts.CanonicalizeSlugs || opts.CompareWithLive) && flag.NArg() == 1 {
+		if err := recoverInterruptedWrite(flag.Arg(0)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// The maintenance pass only takes the JSON file
+	if opts.ConvertLayout != "" {
+		if flag.NArg() != 1 {
+			printUsage()
+			os.Exit(1)
+		}
+		err := convertLayout(flag.Arg(0), opts.ConvertLayout, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error converting the file: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if opts.SortFile {
+		if flag.NArg() != 1 {
+			printUsage()
+			os.Exit(1)
+		}
+		err := sortFile(flag.Arg(0), opts.SortChunkSize, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error sorting the file: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if opts.CanonicalizeSlugs {
+		if flag.NArg() != 1 {
+			printUsage()
+			os.Exit(1)
+		}
+		err := canonicalizeSlugs(flag.Arg(0), opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error canonicalizing slugs: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// The audit fetches every article of the JSON file again
+	if opts.CompareWithLive {
+		if flag.NArg() != 1 {
+			printUsage()
+			os.Exit(1)
+		}
+		ctx := context.Background()
+		if opts.TotalTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opts.TotalTimeout)
+			defer cancel()
+		}
+		err := compareWithLive(ctx, flag.Arg(0), opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error comparing with the live pages: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// A manifest gives the URLs, output and format of the whole job
+	args := flag.Args()
+	if opts.ManifestPath != "" {
+		if len(args) > 0 {
+			fmt.Fprintln(os.Stderr, "Error: with -manifest, the URLs and output are read from the manifest")
+			os.Exit(1)
+		}
+		manifest, err := loadManifest(opts.ManifestPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading manifest: %v\n", err)
+			os.Exit(1)
+		}
+
+		if manifest.Format != "" {
+			opts.Format = manifest.Format
+		}
+		if manifest.DB != "" {
+			opts.DBPath = manifest.DB
+		} else if opts.Format == "sqlite" && opts.DBPath == "" {
+			opts.DBPath = manifest.Output
+		}
+		for _, entry := range manifest.URLs {
+			args = append(args, entry.URL)
+		}
+		if opts.Format == "json" || opts.Format == "frontmatter" {
+			if manifest.Output == "" {
+				fmt.Fprintln(os.Stderr, "Error: the manifest has no output file")
+				os.Exit(1)
+			}
+			args = append(args, manifest.Output)
+		}
+		opts.URLOverrides = manifest.overrides()
+		opts.RequiredFields = manifest.Required
+	}
+
+	// The URL copied to the clipboard comes before the ones given as arguments
+	if opts.Clipboard {
+		url, err := urlFromClipboard()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		args = append([]string{url}, args...)
+	}
+
+	// The JSON index is read like a feed, with one article per item
+	if opts.APIIndex != "" {
+		if !strings.Contains(opts.APIIndex, pagePlaceholder) {
+			fmt.Fprintf(os.Stderr, "Error: the -api-index URL template has no %s placeholder\n", pagePlaceholder)
+			os.Exit(1)
+		}
+		args = append([]string{opts.APIIndex}, args...)
+	}
+
+	// With SQLite output the database is given by -db, so every argument is a URL
+	var urls []string
+	var outputPath string
+	switch opts.Format {
+	case "json", "frontmatter":
+		if len(args) < 2 {
+			printUsage()
+			os.Exit(1)
+		}
+		urls = args[:len(args)-1]
+		outputPath = args[len(args)-1]
+	case "sqlite":
+		if len(args) < 1 || opts.DBPath == "" {
+			printUsage()
+			os.Exit(1)
+		}
+		urls = args
+		outputPath = opts.DBPath
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown output format %q, expected json, sqlite or frontmatter\n", opts.Format)
+		os.Exit(1)
+	}
+	if opts.OutputByCategory && opts.Format != "frontmatter" {
+		warnf("-output-by-category only applies to -format frontmatter")
+	}
+
+	// Catch a directory passed as the output before fetching anything, or a file for front matter
+	targetFiles := []string{outputPath, opts.ArchivePath, opts.FailuresPath, opts.LogPath}
+	if opts.Format == "frontmatter" {
+		if info, err := os.Stat(outputPath); err == nil && !info.IsDir() {
+			fmt.Fprintf(os.Stderr, "Error: target path is a file, expected a directory: %s\n", outputPath)
+			os.Exit(1)
+		}
+		targetFiles = targetFiles[1:]
+	}
+	for _, path := range targetFiles {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			fmt.Fprintf(os.Stderr, "Error: target path is a directory, expected a file: %s\n", path)
+			os.Exit(1)
+		}
+	}
+
+	// A crash during a previous run may have left a temporary file next to a target
+	for _, path := range targetFiles {
+		if path == "" {
+			continue
+		}
+		if err := recoverInterruptedWrite(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	switch opts.Mode {
+	case "append":
+	case "replace":
+		if opts.Format != "json" {
+			fmt.Fprintln(os.Stderr, "Error: -mode replace is only supported with JSON output")
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown mode %q, expected append or replace\n", opts.Mode)
+		os.Exit(1)
+	}
+
+	// Keep appending to an existing file in its own format rather than rewriting it as JSON
+	if opts.Format == "json" && opts.Mode == "append" {
+		opts.FileFormat = detectFileFormat(outputPath)
+		if opts.FileFormat != "" && opts.FileFormat != "json" {
+			if opts.Review {
+				fmt.Fprintf(os.Stderr, "Error: -review is only supported with JSON output, %s is %s\n", outputPath, opts.FileFormat)
+				os.Exit(1)
+			}
+			if opts.Limit > 0 || opts.Update {
+				warnf("-limit and -update only apply to JSON files, %s is %s", outputPath, opts.FileFormat)
+			}
+		}
+	}
+
+	// Detect edits made to the file since the checksum was recorded
+	if opts.VerifyChecksum {
+		opts.ChecksumFile = true
+	}
+	if opts.ChecksumFile && opts.Format == "json" {
+		if err := verifyChecksum(outputPath); err != nil {
+			if opts.VerifyChecksum {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			warnf("%v", err)
+		}
+	}
+
+	// In review mode, articles are added to a copy of the collection in memory
+	if opts.Review {
+		if opts.Format != "json" {
+			fmt.Fprintln(os.Stderr, "Error: -review is only supported with JSON output")
+			os.Exit(1)
+		}
+		review, err := startReview(outputPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", outputPath, err)
+			os.Exit(1)
+		}
+		opts.ReviewState = review
+
+		// Only the diff is printed, so it can be pasted as is
+		opts.Quiet = true
+	}
+
+	// Load the publish dates that override the extracted ones
+	if opts.DatesFile != "" {
+		overrides, err := loadDateOverrides(opts.DatesFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading dates file: %v\n", err)
+			os.Exit(1)
+		}
+		opts.DateOverrides = overrides
+	}
+
+	// Load the fields each og:type must have, which replace the ones of the manifest
+	if opts.RequiredFieldsFile != "" {
+		policy, err := loadRequiredFieldsPolicy(opts.RequiredFieldsFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading required fields policy: %v\n", err)
+			os.Exit(1)
+		}
+		opts.RequiredFields = policy
+	}
+
+	// The total timeout bounds the whole run, cancelling any remaining work
+	ctx := context.Background()
+	if opts.TotalTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.TotalTimeout)
+		defer cancel()
+	}
+
+	// Load the master list that imported articles must not already be in
+	if opts.DedupeAgainst != "" {
+		index, err := fetchMasterIndex(ctx, opts.DedupeAgainst, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading master list: %v\n", err)
+			os.Exit(1)
+		}
+		opts.MasterIndex = index
+	}
+
+	// Each URL of the run is logged for auditing, except in review mode where nothing is written
+	logImport := func(url, result string, articles []OGMetadata, reason string) {
+		if opts.LogPath == "" || opts.ReviewState != nil {
+			return
+		}
+		record := newImportLogRecord(url, result, articles, reason)
+		if err := appendImportLog(opts.LogPath, record, os.FileMode(opts.OutputMode)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing import log: %v\n", err)
+		}
+	}
+
+	failed := 0
+	var extracted []OGMetadata
+	hostCounts := map[string]int{}
+	for i, url := range urls {
+		if ctx.Err() != nil {
+			fmt.Fprintf(os.Stderr, "Total timeout exceeded, skipping %d remaining URL(s)\n", len(urls)-i)
+			failed += len(urls) - i
+			for _, skipped := range urls[i:] {
+				logImport(skipped, importFailed, nil, ctx.Err().Error())
+			}
+
+			// Skipped URLs are recorded so they can be retried
+			if opts.FailuresPath != "" {
+				for _, skipped := range urls[i:] {
+					if recordErr := recordFailure(opts.FailuresPath, skipped, ctx.Err(), os.FileMode(opts.OutputMode)); recordErr != nil {
+						fmt.Fprintf(os.Stderr, "Error recording failure: %v\n", recordErr)
+					}
+				}
+			}
+			break
+		}
+
+		if opts.Exclude.matches(url) {
+			if !opts.Quiet {
+				fmt.Printf("Skipping %s: matches an -exclude pattern\n", url)
+			}
+			logImport(url, importSkipped, nil, "matches an -exclude pattern")
+			continue
+		}
+
+		// Don't fetch more than the allowed number of URLs from a single site
+		if opts.MaxPerHost > 0 {
+			domain := registrableDomain(url)
+			if hostCounts[domain] >= opts.MaxPerHost {
+				if !opts.Quiet {
+					fmt.Printf("Skipping %s: already fetched %d URL(s) from %s\n", url, opts.MaxPerHost, domain)
+				}
+				logImport(url, importSkipped, nil, fmt.Sprintf("already fetched %d URL(s) from %s", opts.MaxPerHost, domain))
+				continue
+			}
+			hostCounts[domain]++
+		}
+
+		articles, err := processURL(ctx, url, outputPath, opts)
+		extracted = append(extracted, articles...)
+
+		// Once replaced, the file collects the rest of the run
+		if len(articles) > 0 {
+			opts.Mode = "append"
+		}
+
+		switch {
+		case err != nil:
+			logImport(url, importFailed, articles, err.Error())
+		case len(articles) == 0:
+			logImport(url, importSkipped, nil, "no article imported")
+		default:
+			logImport(url, importImported, articles, "")
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error processing %s: %v\n", url, err)
+			failed++
+
+			if opts.FailuresPath != "" {
+				if recordErr := recordFailure(opts.FailuresPath, url, err, os.FileMode(opts.OutputMode)); recordErr != nil {
+					fmt.Fprintf(os.Stderr, "Error recording failure: %v\n", recordErr)
+				}
+			}
+
+			// In CI any failure should abort the run, leaving the remaining URLs untouched
+			if opts.FailFast {
+				if remaining := len(urls) - i - 1; remaining > 0 {
+					fmt.Fprintf(os.Stderr, "Stopping at the first failure (-fail-fast), %d URL(s) not processed\n", remaining)
+				}
+				os.Exit(1)
+			}
+		}
+	}
+
+	if opts.ReviewState != nil {
+		err := opts.ReviewState.printDiff(outputPath, opts.Indent.Indent())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error computing the changes: %v\n", err)
+			failed++
+		}
+	}
+
+	if opts.ReportMissing && !opts.Quiet {
+		printMissingReport(extracted)
+	}
+
+	// Report likely duplicates now that all articles are in the collection
+	if opts.FuzzyDedup {
+		if opts.Format != "json" {
+			warnf("-fuzzy-dedup is only supported with JSON output")
+		} else if err := auditDuplicateTitles(outputPath, opts.FuzzyThreshold); err != nil {
+			fmt.Fprintf(os.Stderr, "Error checking for duplicates: %v\n", err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// processURL extracts the metadata from a single URL and appends it to the output file.
+// A feed URL yields one article per item.
+func processURL(ctx context.Context, url string, outputPath string, opts Options) ([]OGMetadata, error) {
+	// Fetch and extract metadata from URL
+	articles, err := extractArticles(ctx, url, opts)
+
+	// Dead links may still be rescued from their latest archived snapshot
+	if err != nil && opts.ViaArchive && ctx.Err() == nil {
+		if !opts.Quiet {
+			fmt.Printf("Fetching %s failed (%v), trying the Wayback Machine\n", url, err)
+		}
+		var archiveErr error
+		articles, archiveErr = extractFromArchive(ctx, url, opts)
+		if archiveErr != nil {
+			err = fmt.Errorf("%w (no archived copy: %v)", err, archiveErr)
+		} else {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error extracting metadata: %w", err)
+	}
+
+	var processed []OGMetadata
+	var missingErr error
+	for _, metadata := range articles {
+		// Apply the publish date override for this slug, if any
+		if date, ok := opts.DateOverrides[metadata.Slug]; ok {
+			metadata.PublishDate = date
+		}
+
+		// Apply the fields set for this URL by the manifest, if any
+		if overrides, ok := opts.URLOverrides[url]; ok {
+			overrides.apply(&metadata)
+		}
+
+		// Feed items are filtered like the URLs given on the command line
+		if metadata.URL != url && opts.Exclude.matches(metadata.URL) {
+			if !opts.Quiet {
+				fmt.Printf("Skipping %s: matches an -exclude pattern\n", metadata.URL)
+			}
+			continue
+		}
+
+		// Paywalled pages usually only expose a teaser
+		if opts.SkipPaywalled && metadata.Paywalled {
+			if !opts.Quiet {
+				fmt.Printf("Skipping %s: the article is paywalled\n", metadata.Slug)
+			}
+			continue
+		}
+
+		// Articles of some types are useless without a given field, such as videos without a video URL
+		if opts.RequiredFields != nil {
+			if missing := opts.RequiredFields.missingFields(metadata); len(missing) > 0 {
+				message := fmt.Sprintf("%s (og:type %s) is missing the required field(s) %s", metadata.Slug, metadata.Type, strings.Join(missing, ", "))
+				if opts.RequiredFields.OnMissing == "fail" {
+					missingErr = errors.Join(missingErr, errors.New(message))
+					continue
+				}
+				warnf("%s", message)
+			}
+		}
+
+		if opts.VerifyImage {
+			verifyImage(ctx, &metadata, opts)
+		}
+
+		if opts.ValidateImageAspect {
+			checkImageAspect(ctx, metadata, opts)
+		}
+
+		// Skip articles that are already in the master list
+		if opts.MasterIndex != nil && opts.MasterIndex.contains(metadata) {
+			if !opts.Quiet {
+				fmt.Printf("Skipping %s: already in the master list\n", metadata.Slug)
+			}
+			continue
+		}
+
+		// Review mode only records the change
+		if opts.ReviewState != nil {
+			opts.ReviewState.add(metadata, opts)
+			processed = append(processed, metadata)
+			opts.Mode = "append"
+			continue
+		}
+
+		target := outputPath
+		switch opts.Format {
+		case "sqlite":
+			// Insert into the articles table of the database
+			err = insertIntoSQLite(metadata, outputPath)
+			if err != nil {
+				return processed, fmt.Errorf("error writing to database: %w", err)
+			}
+		case "frontmatter":
+			// Write a markdown file per article into the output directory
+			target, err = writeFrontMatterFile(metadata, outputPath, opts)
+			if err != nil {
+				return processed, fmt.Errorf("error writing markdown file: %w", err)
+			}
+		default:
+			if opts.FileFormat != "" && opts.FileFormat != "json" {
+				err = appendInFormat(metadata, outputPath, opts.FileFormat, os.FileMode(opts.OutputMode))
+				if err != nil {
+					return processed, fmt.Errorf("error appending to %s file: %w", strings.ToUpper(opts.FileFormat), err)
+				}
+				break
+			}
+
+			// Create backup and append to existing JSON file
+			err = appendToJSONFile(metadata, outputPath, opts)
+			if err != nil {
+				return processed, fmt.Errorf("error appending to JSON file: %w", err)
+			}
+		}
+
+		// Keep the checksum in step with the file, so the next run can detect outside edits
+		if opts.ChecksumFile && opts.Format == "json" {
+			err = writeChecksum(outputPath, os.FileMode(opts.OutputMode))
+			if err != nil {
+				return processed, fmt.Errorf("error updating checksum file: %w", err)
+			}
+		}
+		processed = append(processed, metadata)
+		opts.Mode = "append"
+
+		// Print metadata to console
+		if !opts.Quiet {
+			printMetadata(metadata)
+			fmt.Printf("\nSuccessfully appended to %s\n", target)
+		}
+	}
+
+	return processed, missingErr
+}
+
+// parseFlags parses the command line flags into Options
+func parseFlags() Options {
+	opts := Options{
+		MetaMapping:   mappingFlag{},
+		ImageRewrites: mappingFlag{},
+	}
+
+	// Stored inverted, so the zero value of the options strips the query like the flag default
+	var stripQueryOnSlug bool
+
+	flag.StringVar(&opts.ManifestPath, "manifest", "", "YAML file describing the import job: output file, format, default fields and URLs with per-URL fields")
+	flag.BoolVar(&opts.Clipboard, "clipboard", false, "Read the URL from the system clipboard, so only the JSON file path is needed")
+	flag.IntVar(&opts.Limit, "limit", 0, "Keep only the N most recent articles after appending (0 keeps all)")
+	flag.StringVar(&opts.ArchivePath, "archive", "", "JSON file to append articles removed by -limit to")
+	flag.BoolVar(&opts.ViaArchive, "via-archive", false, "When a URL can't be fetched or extracted, extract from its latest Wayback Machine snapshot instead")
+	flag.BoolVar(&opts.TwoPass, "two-pass", false, "When the page has no og title, description or image, fetch it again as a social network crawler and keep the richer result")
+	flag.BoolVar(&opts.RetryDifferentUA, "retry-different-ua", false, "When a site answers 403, retry once with a browser User-Agent from a rotation list")
+	flag.IntVar(&opts.RetryBudget, "retry-budget", 0, "Maximum number of retries for the whole run, after which failures aren't retried (0 for no limit)")
+	flag.Var(&opts.CanonicalHosts, "canonical-host", "Host whose URLs og:url and the canonical link may point to (repeatable); others are replaced by the fetched URL")
+	flag.Var(&opts.AlternateUAs, "alternate-ua", "User-Agent used by -retry-different-ua instead of the built-in list (repeatable)")
+	flag.Var(&opts.Langs, "lang", "Value of the Accept-Language header sent when fetching the page (e.g. \"de-DE\"); repeat it to also capture the title and description in other languages")
+	flag.BoolVar(&opts.IncludeConfidence, "include-confidence", false, "Record where each core field was found (og, twitter, jsonld, framework-data, html-fallback, url-guess) and a confidence score")
+	flag.BoolVar(&opts.JSONLDOnly, "json-ld-only", false, "Extract the title, description, image, date and author from the JSON-LD data only, ignoring the meta tags and other fallbacks")
+	flag.BoolVar(&opts.FrameworkData, "framework-data", false, "Fill the fields missing from the meta tags from the page state of Next.js (__NEXT_DATA__) and Nuxt (__NUXT__) pages")
+	flag.BoolVar(&opts.IncludeMeta, "include-meta", false, "Include extraction details (fetch time, language) in the output")
+	flag.BoolVar(&opts.Strict, "strict", false, "Treat placeholder descriptions such as \"Read more…\" as missing, and use an excerpt of the article instead")
+	flag.BoolVar(&opts.StripSiteSuffix, "strip-site-suffix", false, "Remove a trailing \" | Site Name\" matching og:site_name from the title")
+	flag.IntVar(&opts.MaxDescLength, "max-desc", 0, "Truncate descriptions longer than N characters at a word boundary, ending with an ellipsis (0 keeps them whole)")
+	flag.BoolVar(&opts.StripHTML, "strip-html", false, "Remove HTML tags and entities from the title and description")
+	flag.BoolVar(&opts.NormalizeWhitespace, "normalize-whitespace", false, "Unescape HTML entities, trim and collapse whitespace in every text field")
+	flag.BoolVar(&opts.NoURLDate, "no-url-date", false, "Never guess the publish date from the URL, leaving it empty when the page doesn't declare one")
+	flag.BoolVar(&stripQueryOnSlug, "strip-query-on-slug", true, "Ignore the query string of the URL when deriving the slug; with =false it is appended to the slug")
+	flag.BoolVar(&opts.FlattenSlugSpaces, "flatten-whitespace-in-slug", false, "Replace whitespace in the slug, literal or encoded as %20 or +, with a single hyphen")
+	flag.IntVar(&opts.MaxSlugLength, "max-slug-length", 0, "Truncate longer slugs to this many characters, at a word boundary (0 for no limit)")
+	flag.BoolVar(&opts.CleanURL, "clean-url", false, "Remove the query string and fragment from the stored URL (the slug is controlled by -strip-query-on-slug)")
+	flag.BoolVar(&opts.PreferCanonicalSlug, "prefer-canonical-for-slug", false, "Derive the slug from the canonical URL of the page, when it has a valid one, instead of the given URL")
+	flag.BoolVar(&opts.DisambiguateSlugs, "disambiguate-slugs", false, "When another article with a different URL already has the slug, append the host or a number to keep both")
+	flag.IntVar(&opts.SlugSegment, "slug-segment", 0, "Path segment used as the slug, counting from 1 (negative values count from the end, -1 is the last)")
+	flag.StringVar(&opts.APIIndex, "api-index", "", "URL template of a paginated JSON listing API, with {page} for the page number; every item of every page is imported")
+	flag.IntVar(&opts.APIMaxPages, "api-max-pages", 50, "Maximum number of pages read by -api-index (0 for no limit)")
+	flag.BoolVar(&opts.PreferJSONAPI, "prefer-json-api", false, "Ask for JSON with the Accept header and map the fields of a JSON response")
+	flag.BoolVar(&opts.TrustFetchedURL, "trust-fetched-url", false, "Keep the fetched URL when og:url points to a different host")
+	flag.BoolVar(&opts.Review, "review", false, "Print the changes to the JSON file as a unified diff instead of writing them")
+	flag.BoolVar(&opts.Verbose, "verbose", false, "Print details such as JSON-LD blocks that could not be parsed")
+	flag.BoolVar(&opts.Quiet, "quiet", false, "Only print warnings and errors")
+	flag.Var(&opts.Exclude, "exclude", "Skip URLs matching this regular expression, such as /tag/ (repeatable)")
+	flag.IntVar(&opts.MaxPerHost, "max-per-host", 0, "Fetch at most N URLs from any single site in a run (0 means no limit)")
+	flag.BoolVar(&opts.FailFast, "fail-fast", false, "Stop at the first URL that fails instead of continuing with the others")
+	flag.StringVar(&opts.LogPath, "log-file", "", "File to append a JSON line (timestamp, url, result, missing fields) to for every processed URL")
+	flag.StringVar(&opts.FailuresPath, "record-failures", "", "JSON file to append a record (url, error, time) of each URL that couldn't be imported to")
+	flag.BoolVar(&opts.ReportMissing, "report-missing", false, "Print how many articles were missing each field at the end of the run")
+	flag.BoolVar(&opts.PrintSchema, "print-schema", false, "Print a JSON Schema of the articles file, then exit")
+	flag.BoolVar(&opts.CountOnly, "count-only", false, "Print the number of articles of the JSON file, streaming it, then exit")
+	flag.BoolVar(&opts.GroupBySource, "group-by-source", false, "Write the JSON file as a map of source to articles instead of a flat list")
+	flag.BoolVar(&opts.SortFile, "sort", false, "Sort the articles of the JSON or NDJSON file by date, newest first, in bounded memory, then exit")
+	flag.IntVar(&opts.SortChunkSize, "sort-chunk-size", 10000, "Number of articles -sort holds in memory at once")
+	flag.StringVar(&opts.ConvertLayout, "convert-layout", "", "Rewrite the JSON file `grouped` by source or as a flat list of articles, then exit")
+	flag.BoolVar(&opts.CanonicalizeSlugs, "canonicalize-slugs", false, "Recompute the slug of every article of the JSON file from its URL, then exit")
+	flag.BoolVar(&opts.CompareWithLive, "compare-with-live", false, "Extract every article of the JSON file again and report the fields that changed on the live page, then exit")
+	flag.BoolVar(&opts.Apply, "apply", false, "With -compare-with-live, update the JSON file with the live values")
+	flag.BoolVar(&opts.SelfTest, "selftest", false, "Extract from a bundled page served locally and check the result, then exit")
+	flag.StringVar(&opts.Mode, "mode", "append", "How articles are written to the JSON file: append, or replace to overwrite its articles (a backup is kept)")
+	flag.StringVar(&opts.Format, "format", "json", "Output format: json, sqlite or frontmatter (a markdown file per article in the output directory)")
+	flag.BoolVar(&opts.OutputByCategory, "output-by-category", false, "With -format frontmatter, write each article into a subdirectory named after its category")
+	flag.StringVar(&opts.DBPath, "db", "", "Path of the SQLite database used with -format sqlite")
+	flag.DurationVar(&opts.FetchTimeout, "fetch-timeout", 30*time.Second, "Timeout for fetching each URL (0 disables it)")
+	flag.DurationVar(&opts.TotalTimeout, "total-timeout", 0, "Deadline for the whole run, after which remaining URLs are skipped (0 disables it)")
+	flag.Var(&opts.TLSMinVersion, "tls-min", "Minimum TLS `version` of the servers fetched from: 1.0, 1.1, 1.2 or 1.3 (default Go's, 1.2)")
+	flag.Var(&opts.TLSCiphers, "tls-ciphers", "Comma-separated cipher suites, as named in crypto/tls, allowed for TLS 1.2 and below")
+	flag.Var(&opts.DefaultTZ, "default-tz", "IANA time zone, such as America/New_York, of dates that don't specify one (default UTC)")
+	flag.StringVar(&opts.DatesFile, "dates-file", "", "JSON object or CSV file mapping slugs to publish dates that override the extracted ones")
+	flag.StringVar(&opts.RequiredFieldsFile, "fields-required-per-type", "", "YAML file listing the fields each og:type must have, such as a publishDate for articles, and whether to warn or fail")
+	flag.BoolVar(&opts.SkipPaywalled, "skip-paywalled", false, "Don't import articles whose JSON-LD marks them as not accessible for free")
+	flag.StringVar(&opts.DedupeAgainst, "dedupe-against", "", "URL of a master articles.json; articles whose slug or URL it already lists are skipped")
+	flag.BoolVar(&opts.FoldURLCase, "fold-url-case", false, "Ignore the case of URL paths when comparing URLs for -dedupe-against")
+	flag.BoolVar(&opts.FuzzyDedup, "fuzzy-dedup", false, "Report articles with highly similar titles after appending (nothing is removed)")
+	flag.Float64Var(&opts.FuzzyThreshold, "fuzzy-threshold", 0.9, "Title similarity (0-1) at which -fuzzy-dedup reports a pair")
+	flag.BoolVar(&opts.ChecksumFile, "checksum-file", false, "Keep a <file>.sha256 checksum of the output file, warning when it was modified outside of a run")
+	flag.BoolVar(&opts.VerifyChecksum, "verify-checksum", false, "Like -checksum-file, but abort when the output file was modified outside of a run")
+	flag.Var(&opts.Indent, "indent", "Indentation of the written JSON: a number of spaces, or \"tab\" (default 2 spaces)")
+	flag.Var(&opts.OutputMode, "output-mode", "Permissions of the written files and backups as an `octal` mode, such as 0600 (default 0644 for new files)")
+	flag.BoolVar(&opts.Update, "update", false, "Replace the article with the same slug instead of appending a duplicate")
+	flag.BoolVar(&opts.MergeByTitle, "merge-into-existing-by-title", false, "Record an article whose title matches an existing one as an alternate URL of it instead of adding a duplicate")
+	flag.BoolVar(&opts.MergeTags, "merge-tags", false, "With -update, keep the existing tags and add the extracted ones instead of replacing them")
+	flag.BoolVar(&opts.AppendArrayOnly, "append-array-only", false, "Splice the article into the existing file without loading the whole collection (for very large files)")
+	flag.BoolVar(&opts.ImgFallback, "img-fallback", false, "Use a large <img> from the page when there is no og:image, twitter:image or JSON-LD image")
+	flag.BoolVar(&opts.ValidateImageAspect, "validate-image-aspect", false, "Warn when the image is too tall or too wide for social cards (outside about 1:1 to 2:1)")
+	flag.BoolVar(&opts.VerifyImage, "verify-image", false, "Drop the image when it can't be fetched, isn't an image or is smaller than -min-image-bytes")
+	flag.IntVar(&opts.MinImageBytes, "min-image-bytes", 1024, "Minimum size in bytes (from Content-Length) of an image kept by -verify-image, 0 to accept any size")
+	flag.IntVar(&opts.ImgMinSize, "img-min-size", 200, "Minimum width and height in pixels of an image picked by -img-fallback")
+	flag.BoolVar(&opts.SaveDataImages, "save-data-images", false, "Save inline data: URI images to the image directory instead of dropping them")
+	flag.StringVar(&opts.SaveHTMLDir, "save-html", "", "Directory where the fetched HTML of each page is saved as <slug>.html")
+	flag.StringVar(&opts.ImageDir, "image-dir", "images", "Directory where images are saved")
+	flag.Var(opts.ImageRewrites, "image-rewrite", "Rewrite the start of image URLs, as \"old-prefix=new-prefix\" (repeatable)")
+	flag.BoolVar(&opts.CleanImageURL, "clean-image-url", false, "Remove resizing parameters such as w, h and q from the query of image URLs, keeping the others")
+	flag.Var(opts.MetaMapping, "map-meta", "Store a meta property under an extras key, as \"property=key\" (repeatable)")
+	flag.Usage = printUsage
+	flag.Parse()
+
+	opts.KeepQueryInSlug = !stripQueryOnSlug
+	opts.Transport = tlsTransport(opts)
+
+	// The first language is the one of the article, the others only add localized text
+	if len(opts.Langs) > 0 {
+		opts.Lang = opts.Langs[0]
+	}
+
+	return opts
+}
+
+func printUsage() {
+	fmt.Println("Usage: og-extractor [options] <url> [<url>...] <json-file-path>")
+	fmt.Println("  url:            URL of the web page to extract Open Graph metadata from (several may be given)")
+	fmt.Println("  json-file-path: Path to the target JSON file to append the metadata to")
+	fmt.Println("\nWith -clipboard, the URL is read from the clipboard: og-extractor -clipboard <json-file-path>")
+	fmt.Println("With -api-index, the articles are read from a paginated JSON API: og-extractor -api-index '<url-with-{page}>' <json-file-path>")
+	fmt.Println("With -manifest, the URLs and output are read from a manifest: og-extractor -manifest <manifest.yaml>")
+	fmt.Println("\nWith -format sqlite, the database is given by -db and every argument is a URL:")
+	fmt.Println("  og-extractor -format sqlite -db <db-path> <url> [<url>...]")
+	fmt.Println("With -format frontmatter, each article is written as <slug>.md into the output directory:")
+	fmt.Println("  og-extractor -format frontmatter [-output-by-category] <url> [<url>...] <output-dir>")
+	fmt.Println("\nTo check that extraction works, run: og-extractor -selftest")
+	fmt.Println("To recompute the slugs of a file, run: og-extractor -canonicalize-slugs <json-file-path>")
+	fmt.Println("To report the articles of a file that changed since their import, run: og-extractor -compare-with-live [-apply] <json-file-path>")
+	fmt.Println("To count the articles of a file, run: og-extractor -count-only <json-file-path>")
+	fmt.Println("To print a JSON Schema of the articles file, run: og-extractor -print-schema")
+	fmt.Println("To sort a file by date, run: og-extractor -sort <json-file-path>")
+	fmt.Println("To group a file by source or flatten it, run: og-extractor -convert-layout grouped|flat <json-file-path>")
+	fmt.Println("\nOptions:")
+	flag.CommandLine.SetOutput(os.Stdout)
+	flag.PrintDefaults()
+	fmt.Println("\nThe target JSON file must follow the structure: {\"articles\":[{...}]}")
+	fmt.Println("A backup of the original file will be created before modification.")
+}
+
+// fileModeFlag is a file permissions flag given in octal, such as 0600
+type fileModeFlag os.FileMode
+
+func (m *fileModeFlag) String() string {
+	if *m == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%#o", uint32(*m))
+}
+
+func (m *fileModeFlag) Set(value string) error {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode == 0 || mode > 0777 {
+		return fmt.Errorf("expected octal permissions such as 0600, got %q", value)
+	}
+	*m = fileModeFlag(mode)
+	return nil
+}
+
+// locationFlag is a time zone flag given as an IANA name, such as America/New_York
+type locationFlag struct {
+	location *time.Location
+}
+
+func (l *locationFlag) String() string {
+	if l.location == nil {
+		return ""
+	}
+	return l.location.String()
+}
+
+func (l *locationFlag) Set(value string) error {
+	location, err := time.LoadLocation(value)
+	if err != nil {
+		return fmt.Errorf("unknown time zone %q", value)
+	}
+	l.location = location
+	return nil
+}
+
+// Location returns the configured time zone, or UTC when none was given
+func (l *locationFlag) Location() *time.Location {
+	if l.location == nil {
+		return time.UTC
+	}
+	return l.location
+}
+
+// indentFlag is the indentation of the written JSON, given as a number of spaces or "tab"
+type indentFlag struct {
+	indent string
+	set    bool
+}
+
+func (i *indentFlag) String() string {
+	if !i.set {
+		return ""
+	}
+	if i.indent == "\t" {
+		return "tab"
+	}
+	return strconv.Itoa(len(i.indent))
+}
+
+func (i *indentFlag) Set(value string) error {
+	if strings.EqualFold(value, "tab") {
+		i.indent, i.set = "\t", true
+		return nil
+	}
+	spaces, err := strconv.Atoi(value)
+	if err != nil || spaces < 0 || spaces > 8 {
+		return fmt.Errorf("expected a number of spaces from 0 to 8 or \"tab\", got %q", value)
+	}
+	i.indent, i.set = strings.Repeat(" ", spaces), true
+	return nil
+}
+
+// Indent returns the configured indentation, or two spaces when none was given
+func (i *indentFlag) Indent() string {
+	if !i.set {
+		return "  "
+	}
+	return i.indent
+}
+
+// stringListFlag is a repeatable string flag
+type stringListFlag []string
+
+func (l *stringListFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringListFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// regexpListFlag is a repeatable regular expression flag
+type regexpListFlag []*regexp.Regexp
+
+func (r *regexpListFlag) String() string {
+	patterns := make([]string, len(*r))
+	for i, re := range *r {
+		patterns[i] = re.String()
+	}
+	return strings.Join(patterns, ",")
+}
+
+func (r *regexpListFlag) Set(pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+	}
+	*r = append(*r, re)
+	return nil
+}
+
+// matches reports whether any of the expressions matches the string
+func (r regexpListFlag) matches(s string) bool {
+	for _, re := range r {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// mappingFlag is a repeatable flag of "name=value" pairs
+type mappingFlag map[string]string
+
+func (m mappingFlag) String() string {
+	pairs := make([]string, 0, len(m))
+	for name, value := range m {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (m mappingFlag) Set(pair string) error {
+	name, value, found := strings.Cut(pair, "=")
+	if !found || name == "" || value == "" {
+		return fmt.Errorf("expected name=value, got %q", pair)
+	}
+	m[name] = value
+	return nil
+}
+
+// extractArticles fetches the URL and extracts its metadata. A page yields a single article,
+// while an RSS or Atom feed yields one article per item.
+func extractArticles(ctx context.Context, url string, opts Options) ([]OGMetadata, error) {
+	// The index template stands for all the items of its pages
+	if opts.APIIndex != "" && url == opts.APIIndex {
+		return extractAPIIndex(ctx, url, opts)
+	}
+	if opts.TwoPass {
+		return extractTwoPass(ctx, url, opts)
+	}
+	return extractArticlesFrom(ctx, url, url, opts)
+}
+
+// extractArticlesFrom is like extractArticles, but fetches the page from fetchURL, such as an
+// archived copy of url. The articles are extracted as if the page had been fetched from url.
+func extractArticlesFrom(ctx context.Context, fetchURL, url string, opts Options) ([]OGMetadata, error) {
+	// Fetch the web page
+	resp, err := fetchPage(ctx, fetchURL, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Some sites refuse the default client but serve the page to browsers
+	if resp.StatusCode == http.StatusForbidden && opts.RetryDifferentUA && takeRetry(url, opts) {
+		resp.Body.Close()
+
+		retryOpts := opts
+		retryOpts.UserAgent = nextAlternateUserAgent(opts.AlternateUAs)
+		if !opts.Quiet {
+			fmt.Printf("Got 403 for %s, retrying with User-Agent %q\n", url, retryOpts.UserAgent)
+		}
+
+		resp, err = fetchPage(ctx, fetchURL, retryOpts)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch URL: status code %d", resp.StatusCode)
+	}
+
+	// A slow page cut off by the deadline still has its head, where the metadata usually is
+	body, truncated, err := readResponse(resp.Body)
+	partial := false
+	if err != nil {
+		if !isTimeout(err) || len(body) == 0 {
+			return nil, err
+		}
+		warnf("%s: deadline exceeded during extraction, partial result (%d bytes read)", url, len(body))
+		partial = true
+	}
+
+	if isFeed(resp.Header.Get("Content-Type"), body) {
+		return extractFeedArticles(body, url, opts)
+	}
+
+	if opts.PreferJSONAPI && isJSONResponse(resp.Header.Get("Content-Type")) {
+		if truncated {
+			return nil, fmt.Errorf("JSON API response is larger than %d MB", maxResponseBytes>>20)
+		}
+		metadata, err := extractJSONAPIArticle(body, url, opts)
+		if err != nil {
+			return nil, err
+		}
+		return []OGMetadata{metadata}, nil
+	}
+
+	// The metadata is in the head, so the start of a huge page is enough
+	if truncated {
+		warnf("%s: page larger than %d MB, only its beginning is extracted", url, maxResponseBytes>>20)
+	}
+
+	// The parser expects UTF-8, whatever the page is encoded in
+	page := decodePage(body, resp.Header.Get("Content-Type"), url, opts)
+
+	// Strict XHTML may self-close elements that the HTML parser expects to be closed explicitly
+	if isXHTML(resp.Header.Get("Content-Type")) {
+		page = expandSelfClosingTags(page)
+	}
+
+	metadata, err := extractOGMetadata(bytes.NewReader(page), url, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Some sites only declare the canonical and author links in HTTP headers
+	applyLinkHeaders(&metadata, resp.Header.Values("Link"), url)
+
+	// Without a language declared by the page, the server's is a cheap and reliable signal
+	if metadata.Locale == "" {
+		metadata.Locale = contentLanguage(resp.Header.Get("Content-Language"))
+	}
+	checkCanonicalHosts(&metadata, url, opts)
+	preferCanonicalSlug(&metadata, opts)
+
+	// With several languages, the page is fetched again for each of the others
+	if len(opts.Langs) > 1 && !partial {
+		metadata.Localized = extractLocalizedText(ctx, url, metadata, opts)
+	}
+
+	// Keep the raw page so the extraction can be re-run offline
+	if opts.SaveHTMLDir != "" {
+		err = saveRawHTML(body, metadata.Slug, opts.SaveHTMLDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to save HTML: %w", err)
+		}
+	}
+
+	return []OGMetadata{metadata}, nil
+}
+
+// isTimeout reports whether the error is a deadline being exceeded, either the one of the whole
+// run (-total-timeout) or the one of the request (-fetch-timeout)
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// saveRawHTML writes the fetched page to <dir>/<slug>.html
+func saveRawHTML(body []byte, slug, dir string) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, slug+".html"), body, 0644)
+}
+
+// extractOGMetadata extracts the metadata from the HTML of the page fetched from url
+func extractOGMetadata(body io.Reader, url string, opts Options) (OGMetadata, error) {
+	metadata := OGMetadata{}
+	
+	// Extract slug from URL
+	slug, err := slugFor(url, opts)
+	if err != nil {
+		return metadata, err
+	}
+	metadata.Slug = slug
+
+	// Parse HTML
+	doc, err := html.Parse(body)
+	if err != nil {
+		return metadata, err
+	}
+
+	// Contents of the JSON-LD blocks, used for the fields missing from the meta tags
+	var jsonLDBlocks []string
+	var frameworkBlocks []string
+
+	// Date of the first <time datetime="..."> element, used when no other date is found
+	var timeElementDate string
+
+	// Whether any og: meta tag was found, to detect pages rendered by JavaScript
+	var foundOGTags bool
+
+	// Image from the Twitter card, used when og:image is missing
+	var twitterImage string
+
+	// The og:image blocks, each with its own sub-properties
+	var imageBlocks []ImageInfo
+
+	// First value of each meta property, for the fields that depend on og:type
+	metaProperties := map[string]string{}
+
+	// Where each core field was found, with -include-confidence
+	confidence := newConfidenceTracker(opts)
+
+	// The site's icon, used as the publisher logo when the JSON-LD data has none
+	var touchIcon string
+
+	// The language of the <html> element, used when there is no og:locale
+	var htmlLang string
+
+	// Extract Open Graph metadata
+	var extractMetadata func(*html.Node)
+	extractMetadata = func(n *html.Node) {
+		if n.Type == html.ElementNode && localName(n.Data) == "html" {
+			for _, attr := range n.Attr {
+				if localName(attr.Key) == "lang" && htmlLang == "" {
+					htmlLang = normalizeLocale(attr.Val)
+				}
+			}
+		}
+
+		// Look for the canonical and author links
+		if n.Type == html.ElementNode && localName(n.Data) == "link" {
+			rels, href := linkRelations(n)
+			for _, rel := range rels {
+				if rel == "canonical" && metadata.CanonicalURL == "" && href != "" {
+					metadata.CanonicalURL = resolveURL(url, href)
+				}
+				if rel == "author" && metadata.AuthorURL == "" && href != "" {
+					metadata.AuthorURL = resolveURL(url, href)
+				}
+				// The parts of a serialized post link to each other
+				if rel == "next" && metadata.NextURL == "" && href != "" {
+					metadata.NextURL = resolveURL(url, href)
+				}
+				if (rel == "prev" || rel == "previous") && metadata.PrevURL == "" && href != "" {
+					metadata.PrevURL = resolveURL(url, href)
+				}
+				if (rel == "apple-touch-icon" || rel == "apple-touch-icon-precomposed") && touchIcon == "" && href != "" {
+					touchIcon = resolveURL(url, href)
+				}
+			}
+		}
+
+		if n.Type == html.ElementNode && localName(n.Data) == "meta" {
+			var property, content, value string
+			for _, attr := range n.Attr {
+				// XHTML pages may prefix the attributes with a namespace
+				key := localName(attr.Key)
+				if key == "property" || key == "name" {
+					property = attr.Val
+				}
+				if key == "content" {
+					content = attr.Val
+				}
+				if key == "value" {
+					value = attr.Val
+				}
+			}
+
+			// A few pages put the value in a "value" attribute, used only when content is empty
+			if strings.TrimSpace(content) == "" {
+				content = value
+			}
+
+			if strings.HasPrefix(property, "og:") {
+				foundOGTags = true
+			}
+			if _, seen := metaProperties[property]; !seen && property != "" {
+				metaProperties[property] = content
+			}
+
+			// Store custom mapped properties in the extras map
+			if key, ok := opts.MetaMapping[property]; ok && content != "" {
+				if metadata.Extras == nil {
+					metadata.Extras = map[string]string{}
+				}
+				metadata.Extras[key] = content
+			}
+
+			// With -json-ld-only, the meta tags don't set any field besides the mapped extras
+			if opts.JSONLDOnly {
+				property = ""
+			}
+
+			// Attribute the core fields set by this meta tag to its source
+			before := confidence.snapshot(metadata)
+			switch property {
+			case "og:url":
+				metadata.URL = content
+			case "og:title":
+				metadata.Title = content
+			case "og:description":
+				metadata.Description = content
+			case "og:image", "og:image:url", "og:image:secure_url", "og:image:alt", "og:image:width", "og:image:height", "og:image:type":
+				imageBlocks = addImageProperty(imageBlocks, property, content)
+			case "og:site_name":
+				metadata.Source = content
+			case "og:locale":
+				metadata.Locale = normalizeLocale(content)
+			case "twitter:image", "twitter:image:src":
+				if twitterImage == "" {
+					twitterImage = content
+				}
+			case "twitter:site":
+				metadata.TwitterSite = normalizeTwitterHandle(content)
+			case "twitter:creator":
+				metadata.TwitterCreator = normalizeTwitterHandle(content)
+			case "author":
+				metadata.Author = content
+			case "article:tag":
+				if content != "" {
+					metadata.Tags = articlesjson.MergeTags(metadata.Tags, []string{content})
+				}
+			case "keywords", "news_keywords":
+				metadata.Tags = articlesjson.MergeTags(metadata.Tags, splitKeywords(content))
+			case "article:section":
+				metadata.Category = content
+			case "article:published_time", "datePublished", "pubdate", "publishdate", "DC.date.issued", "article:modified_time":
+				if metadata.PublishDate == "" {
+					metadata.PublishDate = content
+				}
+			}
+			confidence.attribute(&metadata, before, metaPropertySource(property))
+		}
+
+		// Look for a <time> element with a machine-readable date
+		if n.Type == html.ElementNode && localName(n.Data) == "time" && timeElementDate == "" {
+			for _, attr := range n.Attr {
+				if localName(attr.Key) == "datetime" {
+					timeElementDate = normalizeDate(attr.Val, opts.DefaultTZ.Location())
+					break
+				}
+			}
+		}
+
+		// Look for LD+JSON data that might contain publication date
+		if n.Type == html.ElementNode && localName(n.Data) == "script" {
+			var isJSON bool
+			for _, attr := range n.Attr {
+				if localName(attr.Key) == "type" && (attr.Val == "application/ld+json" || attr.Val == "application/json") {
+					isJSON = true
+					break
+				}
+			}
+
+			if isJSON && n.FirstChild != nil {
+				jsonLDBlocks = append(jsonLDBlocks, n.FirstChild.Data)
+			}
+			if state, ok := frameworkDataScript(n); ok && opts.FrameworkData {
+				frameworkBlocks = append(frameworkBlocks, state)
+			}
+		}
+
+		// Recursively process all child nodes
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			extractMetadata(c)
+		}
+	}
+
+	extractMetadata(doc)
+
+	// Derive the slug from the canonical URL rather than the link that was given, if asked to
+	checkCanonicalHosts(&metadata, url, opts)
+	preferCanonicalSlug(&metadata, opts)
+
+	// The first og:image is the primary image
+	if len(imageBlocks) > 0 {
+		confidence.track(&metadata, sourceOG, func() { metadata.Image = imageBlocks[0].URL })
+		metadata.ImageType = imageBlocks[0].Type
+		metadata.ImageObjects = detailedImageBlocks(imageBlocks)
+	}
+
+	// Without OG tags, an empty application shell explains why nothing was found
+	if !foundOGTags && isLikelySPAShell(doc) {
+		warnf("%s: page appears to require JavaScript; OG tags not found in initial HTML", url)
+	}
+
+	// With -json-ld-only, the fields come from the JSON-LD data alone
+	if opts.JSONLDOnly {
+		confidence.track(&metadata, sourceJSONLD, func() { extractOnlyFromJSONLD(jsonLDBlocks, &metadata, url, opts) })
+		handleDataImage(&metadata, opts)
+		applyOutputOptions(&metadata, opts)
+		confidence.apply(&metadata)
+		return metadata, nil
+	}
+
+	// Video, article and profile pages have their own properties
+	confidence.track(&metadata, sourceOG, func() { applyTypeExtractor(metaProperties, &metadata) })
+
+	// Some sites only expose their title in a known place of the page
+	confidence.track(&metadata, sourceHTMLFallback, func() { applySiteExtractor(doc, url, &metadata) })
+
+	// Prefer the Twitter card image over the JSON-LD one when og:image is missing
+	if metadata.Image == "" {
+		confidence.track(&metadata, sourceTwitter, func() { metadata.Image = twitterImage })
+	}
+
+	// Fill in the date, image, author and word count from the JSON-LD data
+	confidence.track(&metadata, sourceJSONLD, func() { extractFromJSONLD(jsonLDBlocks, &metadata, url, opts) })
+	if metadata.PublisherLogo == "" {
+		metadata.PublisherLogo = touchIcon
+	}
+	if metadata.Locale == "" {
+		metadata.Locale = htmlLang
+	}
+
+	// Server-rendered Next.js and Nuxt pages often have cleaner values in their page state
+	confidence.track(&metadata, sourceFramework, func() { extractFromFrameworkData(frameworkBlocks, &metadata, url, opts) })
+
+	// Without any metadata naming it, the <title> element is the best guess of the title, though it
+	// often carries the site name too
+	if metadata.Title == "" {
+		confidence.track(&metadata, sourceTitle, func() { metadata.Title = pageTitle(doc) })
+	}
+
+	// Flag descriptions that don't describe the article, replacing them with -strict
+	confidence.track(&metadata, sourceHTMLFallback, func() { checkDescription(doc, metaProperties, &metadata, url, opts) })
+
+	// As a last resort, use a large image from the page content
+	if metadata.Image == "" && opts.ImgFallback {
+		confidence.track(&metadata, sourceHTMLFallback, func() { metadata.Image = findContentImage(doc, url, opts.ImgMinSize) })
+	}
+
+	// Inline data: URI images are dropped or saved to a file
+	handleDataImage(&metadata, opts)
+
+	// A relative og:url is resolved against the page, a malformed one is replaced by the fetched URL.
+	// The slug comes from the fetched URL, or the canonical link with -prefer-canonical-for-slug,
+	// never from og:url, so it isn't affected either way.
+	if metadata.URL != "" && !isAbsoluteURL(metadata.URL) {
+		resolved := resolveURL(url, metadata.URL)
+		if !isAbsoluteURL(resolved) {
+			warnf("ignoring invalid og:url %q", metadata.URL)
+			resolved = url
+		}
+		metadata.URL = resolved
+	}
+
+	// An og:url on another host usually means a syndicated copy or a templating bug
+	if isAbsoluteURL(metadata.URL) && !articlesjson.SameHost(metadata.URL, url) {
+		warnf("og:url %s points to a different host than the fetched URL %s", metadata.URL, url)
+		if opts.TrustFetchedURL {
+			metadata.URL = url
+		}
+	}
+	
+	// If we couldn't find a date in metadata, try the <time> element and then the URL
+	if metadata.PublishDate == "" {
+		confidence.track(&metadata, sourceHTMLFallback, func() { metadata.PublishDate = timeElementDate })
+	}
+	// A date-like path segment may be a category rather than a date, so -no-url-date skips the guess
+	if metadata.PublishDate == "" && !opts.NoURLDate {
+		confidence.track(&metadata, sourceURLGuess, func() { metadata.PublishDate = extractDateFromURL(url) })
+	}
+
+	applyOutputOptions(&metadata, opts)
+	confidence.apply(&metadata)
+	
+	return metadata, nil
+}
+
+// applyOutputOptions applies the options that clean up or annotate the extracted metadata
+func applyOutputOptions(metadata *OGMetadata, opts Options) {
+	if opts.CleanURL {
+		metadata.URL = stripQuery(metadata.URL)
+	}
+
+	// Resizing parameters make the same image look like different ones
+	if opts.CleanImageURL {
+		metadata.Image = cleanImageURL(metadata.Image)
+		metadata.ImageObjects = cleanImageBlocks(metadata.ImageObjects)
+	}
+
+	if len(opts.ImageRewrites) > 0 {
+		metadata.Image = rewriteImageURL(metadata.Image, opts.ImageRewrites)
+	}
+
+	applyTextOptions(metadata, opts)
+
+	if opts.IncludeMeta {
+		metadata.Meta = &ExtractionMeta{
+			FetchedAt: time.Now().UTC().Format(time.RFC3339),
+			Lang:      opts.Lang,
+		}
+	}
+}
+
+// applyTextOptions applies the options that clean up the text of the title and description
+func applyTextOptions(metadata *OGMetadata, opts Options) {
+	if opts.StripHTML {
+		metadata.Title = stripHTML(metadata.Title)
+		metadata.Description = stripHTML(metadata.Description)
+	}
+
+	if opts.StripSiteSuffix {
+		metadata.Title = stripSiteSuffix(metadata.Title, metadata.Source)
+	}
+
+	if opts.MaxDescLength > 0 {
+		metadata.Description = truncateAtWord(metadata.Description, opts.MaxDescLength)
+	}
+
+	if opts.NormalizeWhitespace {
+		normalizeWhitespace(metadata)
+	}
+}
+
+// maxResponseBytes bounds how much of a page or JSON API response is read, so a huge or endless
+// response can't exhaust the memory
+const maxResponseBytes = 20 << 20
+
+// readResponse reads the body up to maxResponseBytes, reporting whether it was cut off there
+func readResponse(body io.Reader) ([]byte, bool, error) {
+	data, err := io.ReadAll(io.LimitReader(body, maxResponseBytes+1))
+	if len(data) > maxResponseBytes {
+		return data[:maxResponseBytes], true, err
+	}
+	return data, false, err
+}
+
+// fetchPage requests the web page, sending the headers configured in the options
+func fetchPage(ctx context.Context, url string, opts Options) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Lang != "" {
+		req.Header.Set("Accept-Language", opts.Lang)
+	}
+	if opts.UserAgent != "" {
+		req.Header.Set("User-Agent", opts.UserAgent)
+	}
+
+	// Ask sites that can describe the article as JSON to do so, still accepting HTML
+	if opts.PreferJSONAPI {
+		req.Header.Set("Accept", "application/json, text/html;q=0.9, */*;q=0.8")
+	}
+	if opts.Accept != "" {
+		req.Header.Set("Accept", opts.Accept)
+	}
+
+	client := &http.Client{Timeout: opts.FetchTimeout, Transport: opts.Transport}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, tlsPolicyError(err, opts)
+	}
+	return resp, nil
+}
+
+// defaultAlternateUserAgents are the browser User-Agents tried by -retry-different-ua
+var defaultAlternateUserAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_4) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Safari/605.1.15",
+	"Mozilla/5.0 (X11; Linux x86_64; rv:125.0) Gecko/20100101 Firefox/125.0",
+}
+
+// alternateUserAgentIndex is the position of the next User-Agent in the rotation
+var alternateUserAgentIndex int
+
+// nextAlternateUserAgent returns the next User-Agent of the rotation, from the configured
+// list or, when none was given, the default one, so successive retries spread across them
+func nextAlternateUserAgent(configured []string) string {
+	userAgents := configured
+	if len(userAgents) == 0 {
+		userAgents = defaultAlternateUserAgents
+	}
+	userAgent := userAgents[alternateUserAgentIndex%len(userAgents)]
+	alternateUserAgentIndex++
+	return userAgent
+}
+
+// retriesUsed counts the retries made during the run, across all URLs, and retryBudgetSpent
+// records that -retry-budget ran out
+var (
+	retriesUsed      int
+	retryBudgetSpent bool
+)
+
+// takeRetry reports whether a failed fetch of url may be retried, counting the retry against
+// -retry-budget. Once the budget is spent, no URL of the run is retried again, which is
+// logged the first time.
+func takeRetry(url string, opts Options) bool {
+	if opts.RetryBudget > 0 && retriesUsed >= opts.RetryBudget {
+		if !retryBudgetSpent {
+			warnf("retry budget of %d exhausted, not retrying %s or any later failure", opts.RetryBudget, url)
+			retryBudgetSpent = true
+		}
+		return false
+	}
+	retriesUsed++
+	return true
+}
+
+// isAbsoluteURL reports whether the string is an absolute http(s) URL
+func isAbsoluteURL(rawURL string) bool {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// resolveURL resolves a possibly relative reference against the page URL
+func resolveURL(pageURL, ref string) string {
+	base, err := neturl.Parse(pageURL)
+	if err != nil {
+		return ref
+	}
+	u, err := base.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+// normalizeTwitterHandle returns a Twitter handle as "@name", whether it was given as
+// "name", "@name" or a profile URL such as https://twitter.com/name
+func normalizeTwitterHandle(handle string) string {
+	handle = strings.TrimSpace(handle)
+	for _, prefix := range []string{"https://", "http://", "www.", "mobile.", "twitter.com/", "x.com/"} {
+		if len(handle) >= len(prefix) && strings.EqualFold(handle[:len(prefix)], prefix) {
+			handle = handle[len(prefix):]
+		}
+	}
+	handle = strings.TrimPrefix(strings.TrimRight(handle, "/"), "@")
+	if handle == "" {
+		return ""
+	}
+	return "@" + handle
+}
+
+// registrableDomain returns the registrable domain of the URL's host, such as example.co.uk
+// for blog.example.co.uk, or the host itself when it has none (IP addresses, localhost)
+func registrableDomain(url string) string {
+	parsedURL, err := neturl.Parse(url)
+	if err != nil {
+		return url
+	}
+
+	host := strings.ToLower(parsedURL.Hostname())
+	if net.ParseIP(host) != nil {
+		return host
+	}
+
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
+
+// warnf prints a warning message to stderr
+func warnf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "Warning: "+format+"\n", args...)
+}
+
+// spaRootIDs are the ids of the elements JavaScript frameworks commonly render into
+var spaRootIDs = map[string]bool{
+	"app":    true,
+	"root":   true,
+	"__next": true,
+	"__nuxt": true,
+}
+
+// isLikelySPAShell reports whether the page looks like a single-page application shell:
+// almost no text in the body and an empty mount point such as <div id="app">
+func isLikelySPAShell(doc *html.Node) bool {
+	var textLength int
+	var hasRoot bool
+
+	var inspect func(*html.Node)
+	inspect = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "script", "style", "noscript", "template", "head":
+				return
+			case "div":
+				for _, attr := range n.Attr {
+					if attr.Key == "id" && spaRootIDs[attr.Val] {
+						hasRoot = true
+					}
+				}
+			}
+		}
+		if n.Type == html.TextNode {
+			textLength += len(strings.TrimSpace(n.Data))
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			inspect(c)
+		}
+	}
+	inspect(doc)
+
+	return hasRoot && textLength < 200
+}
+
+// normalizeWhitespace unescapes HTML entities, trims and collapses the whitespace of every
+// string field of the metadata, including tags and extras
+func normalizeWhitespace(metadata *OGMetadata) {
+	normalize := func(value string) string {
+		return strings.Join(strings.Fields(html.UnescapeString(value)), " ")
+	}
+
+	fields := reflect.ValueOf(metadata).Elem()
+	for i := 0; i < fields.NumField(); i++ {
+		field := fields.Field(i)
+		switch {
+		case field.Kind() == reflect.String:
+			field.SetString(normalize(field.String()))
+		case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+			for j := 0; j < field.Len(); j++ {
+				field.Index(j).SetString(normalize(field.Index(j).String()))
+			}
+		}
+	}
+
+	for key, value := range metadata.Extras {
+		metadata.Extras[key] = normalize(value)
+	}
+}
+
+// siteSuffixSeparators are the separators placed between a headline and the site name
+var siteSuffixSeparators = []string{" | ", " - ", " — ", " – ", " · "}
+
+// stripSiteSuffix removes a trailing " | Site Name" from the title, only when it exactly matches
+// the site name (ignoring case) and a headline remains before it
+func stripSiteSuffix(title, siteName string) string {
+	siteName = strings.TrimSpace(siteName)
+	if siteName == "" {
+		return title
+	}
+
+	trimmed := strings.TrimSpace(title)
+	for _, separator := range siteSuffixSeparators {
+		suffix := separator + siteName
+		if len(trimmed) <= len(suffix) || !strings.EqualFold(trimmed[len(trimmed)-len(suffix):], suffix) {
+			continue
+		}
+		if headline := strings.TrimSpace(trimmed[:len(trimmed)-len(suffix)]); headline != "" {
+			return headline
+		}
+	}
+	return title
+}
+
+// truncateAtWord shortens the text to at most maxLength characters (runes), cutting at the last
+// word boundary and ending with an ellipsis. Shorter texts are returned unchanged.
+func truncateAtWord(text string, maxLength int) string {
+	runes := []rune(text)
+	if len(runes) <= maxLength {
+		return text
+	}
+
+	// Leave room for the ellipsis, and back off to the last space unless the cut falls between words
+	cut := string(runes[:maxLength-1])
+	if !unicode.IsSpace(runes[maxLength-1]) {
+		if i := strings.LastIndexFunc(cut, unicode.IsSpace); i > 0 {
+			cut = cut[:i]
+		}
+	}
+
+	return strings.TrimRightFunc(cut, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "…"
+}
+
+// stripHTML converts a snippet that may contain HTML markup and entities to plain text
+func stripHTML(snippet string) string {
+	doc, err := html.Parse(strings.NewReader(snippet))
+	if err != nil {
+		return snippet
+	}
+
+	var text strings.Builder
+	var collectText func(*html.Node)
+	collectText = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			text.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collectText(c)
+		}
+	}
+	collectText(doc)
+
+	// Collapse the whitespace left behind by removed tags
+	return strings.Join(strings.Fields(text.String()), " ")
+}
+
+// extractSlug extracts the slug from a URL
+func extractSlug(url string) string {
+	// Remove protocol (http://, https://)
+	cleanURL := url
+	if idx := strings.Index(cleanURL, "://"); idx != -1 {
+		cleanURL = cleanURL[idx+3:]
+	}
+	
+	// Remove query string and fragment
+	if idx := strings.Index(cleanURL, "?"); idx != -1 {
+		cleanURL = cleanURL[:idx]
+	}
+	if idx := strings.Index(cleanURL, "#"); idx != -1 {
+		cleanURL = cleanURL[:idx]
+	}
+	
+	// Remove trailing slash if present
+	cleanURL = strings.TrimSuffix(cleanURL, "/")
+	
+	// Split by slashes and take the last section
+	parts := strings.Split(cleanURL, "/")
+	if len(parts) > 0 && parts[len(parts)-1] != "" {
+		return parts[len(parts)-1]
+	} else if len(parts) > 1 {
+		// If the URL ends with a slash, take the second-to-last non-empty part
+		for i := len(parts) - 2; i >= 0; i-- {
+			if parts[i] != "" {
+				return parts[i]
+			}
+		}
+	}
+	
+	// If we can't find a valid slug, return the domain
+	domainParts := strings.Split(cleanURL, ".")
+	if len(domainParts) > 0 {
+		return domainParts[0]
+	}
+	
+	return ""
+}
+
+// preferCanonicalSlug derives the slug from the canonical URL instead of the fetched one with
+// -prefer-canonical-for-slug, so links with tracking parameters or alternate paths get the same
+// slug as the article. Without a valid canonical URL, the slug is kept.
+func preferCanonicalSlug(metadata *OGMetadata, opts Options) {
+	if !opts.PreferCanonicalSlug || !isAbsoluteURL(metadata.CanonicalURL) {
+		return
+	}
+
+	slug, err := slugFor(metadata.CanonicalURL, opts)
+	if err != nil {
+		warnf("%v, keeping slug %q", err, metadata.Slug)
+		return
+	}
+	metadata.Slug = slug
+}
+
+// slugFor returns the slug of the URL: its last path segment or the one chosen with -slug-segment,
+// followed with -strip-query-on-slug=false by the query string, so pages told apart only by their
+// query (such as "?p=123") get different slugs
+func slugFor(url string, opts Options) (string, error) {
+	slug := extractSlug(url)
+	if opts.SlugSegment != 0 {
+		var err error
+		slug, err = extractSlugSegment(url, opts.SlugSegment)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if opts.KeepQueryInSlug {
+		if query := slugQuery(url); query != "" {
+			slug += "-" + query
+		}
+	}
+	if opts.FlattenSlugSpaces {
+		slug = strings.Trim(slugWhitespace.ReplaceAllString(slug, "-"), "-")
+	}
+	if opts.MaxSlugLength > 0 {
+		slug = truncateSlug(slug, opts.MaxSlugLength)
+	}
+	return slug, nil
+}
+
+// slugWhitespace matches the runs of whitespace of a slug, literal or encoded in the URL as
+// %20 or +, along with the hyphens around them
+var slugWhitespace = regexp.MustCompile(`(?i)-*(?:\s|%20|%09|\+)(?:\s|%20|%09|\+|-)*`)
+
+// slugWordSeparators separate the words of a slug
+const slugWordSeparators = "-_."
+
+// truncateSlug shortens the slug to at most maxLength characters, cutting after the last whole
+// word that fits and removing the separators left at the end. A first word longer than the limit
+// can't be kept whole, so it is cut at the limit.
+func truncateSlug(slug string, maxLength int) string {
+	runes := []rune(slug)
+	if len(runes) <= maxLength {
+		return slug
+	}
+
+	cut := string(runes[:maxLength])
+	if !strings.ContainsRune(slugWordSeparators, runes[maxLength]) {
+		if i := strings.LastIndexAny(cut, slugWordSeparators); i > 0 {
+			cut = cut[:i]
+		}
+	}
+	if trimmed := strings.TrimRight(cut, slugWordSeparators); trimmed != "" {
+		cut = trimmed
+	}
+	return cut
+}
+
+// nonSlugCharacters matches the runs of characters of a query string that don't belong in a slug
+var nonSlugCharacters = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// slugQuery returns the query string of the URL with its separators replaced by dashes,
+// turning "?p=123&lang=en" into "p-123-lang-en"
+func slugQuery(rawURL string) string {
+	parsed, err := neturl.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	query, err := neturl.QueryUnescape(parsed.RawQuery)
+	if err != nil {
+		query = parsed.RawQuery
+	}
+	return strings.Trim(nonSlugCharacters.ReplaceAllString(query, "-"), "-")
+}
+
+// stripQuery removes the query string and fragment from the URL, for -clean-url
+func stripQuery(rawURL string) string {
+	if idx := strings.IndexAny(rawURL, "?#"); idx != -1 {
+		return rawURL[:idx]
+	}
+	return rawURL
+}
+
+// extractSlugSegment returns the path segment at the given position, counting from 1 or,
+// for negative positions, from the end of the path
+func extractSlugSegment(rawURL string, position int) (string, error) {
+	u, err := neturl.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(u.Path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	index := position - 1
+	if position < 0 {
+		index = len(segments) + position
+	}
+	if index < 0 || index >= len(segments) {
+		return "", fmt.Errorf("slug segment %d doesn't exist in %s (%d path segments)", position, rawURL, len(segments))
+	}
+
+	return segments[index], nil
+}
+
+// articleTypes are the schema.org types of JSON-LD nodes describing the article itself
+var articleTypes = map[string]bool{
+	"Article":            true,
+	"NewsArticle":        true,
+	"BlogPosting":        true,
+	"TechArticle":        true,
+	"ScholarlyArticle":   true,
+	"Report":             true,
+	"SocialMediaPosting": true,
+	"LiveBlogPosting":    true,
+}
+
+// ignoredJSONLDTypes are the schema.org types of JSON-LD nodes describing the site or its navigation
+var ignoredJSONLDTypes = map[string]bool{
+	"BreadcrumbList":        true,
+	"WebSite":               true,
+	"Organization":          true,
+	"SiteNavigationElement": true,
+}
+
+// extractFromJSONLD fills the fields not found in the meta tags from the page's JSON-LD blocks.
+// A page often has several blocks (breadcrumbs, site, article), so Article-typed nodes are
+// preferred and nodes describing the site or navigation are ignored.
+func extractFromJSONLD(blocks []string, metadata *OGMetadata, url string, opts Options) {
+	var articles, others, breadcrumbs, organizations []map[string]interface{}
+	for i, block := range blocks {
+		nodes, err := parseJSONLDNodes(block)
+		if err != nil && opts.Verbose {
+			fmt.Fprintf(os.Stderr, "%s: ignoring JSON-LD block %d: %v\n", url, i+1, err)
+		}
+		for _, node := range nodes {
+			switch {
+			case hasJSONLDType(node, articleTypes):
+				articles = append(articles, node)
+			case hasJSONLDType(node, ignoredJSONLDTypes):
+				if hasJSONLDType(node, map[string]bool{"BreadcrumbList": true}) {
+					breadcrumbs = append(breadcrumbs, node)
+				}
+				if hasJSONLDType(node, map[string]bool{"Organization": true}) {
+					organizations = append(organizations, node)
+				}
+			default:
+				others = append(others, node)
+			}
+		}
+	}
+
+	// Look for common date fields in schema.org and other formats
+	dateFields := []string{"datePublished", "dateCreated", "publishedTime", "dateModified", "pubDate"}
+
+	for _, node := range append(articles, others...) {
+		if metadata.PublishDate == "" {
+			for _, field := range dateFields {
+				if dateStr, ok := node[field].(string); ok && dateStr != "" {
+					metadata.PublishDate = dateStr
+					break
+				}
+			}
+		}
+		if metadata.Image == "" {
+			metadata.Image = imageFromJSONValue(node["image"])
+		}
+		if metadata.Author == "" {
+			metadata.Author = authorFromJSONValue(node["author"])
+		}
+		if metadata.PublisherLogo == "" {
+			metadata.PublisherLogo = publisherLogo(node["publisher"], organizations, url)
+		}
+		if metadata.WordCount == 0 {
+			metadata.WordCount = int(jsonNumber(node["wordCount"]))
+		}
+		if isAccessibleForFree(node) == "false" {
+			metadata.Paywalled = true
+		}
+		// Keywords add to the meta tags rather than replacing them
+		metadata.Tags = articlesjson.MergeTags(metadata.Tags, splitKeywords(node["keywords"]))
+		if metadata.OriginalURL == "" && metadata.OriginalSource == "" && metadata.OriginalDate == "" {
+			extractOriginal(node, metadata, dateFields)
+		}
+	}
+
+	// Otherwise the site's Organization node usually describes the publisher
+	for _, node := range organizations {
+		if metadata.PublisherLogo != "" {
+			break
+		}
+		metadata.PublisherLogo = publisherLogo(node, nil, url)
+	}
+
+	// Without article:section, the breadcrumbs often give the section the article is filed under
+	for _, node := range breadcrumbs {
+		if metadata.Category != "" {
+			break
+		}
+		metadata.Category = categoryFromBreadcrumbs(node)
+	}
 }
 
-// ArticlesCollection represents the structure of the target JSON file
-type ArticlesCollection struct {
-	Articles []OGMetadata `json:"articles"`
-}
+// extractOnlyFromJSONLD fills the fields from the JSON-LD data alone, for -json-ld-only. Besides
+// what extractFromJSONLD reads, the title is taken from the headline (or else the name) of the
+// article and the description from its description. The URL is the fetched one, made absolute
+// when the data gives its own.
+func extractOnlyFromJSONLD(blocks []string, metadata *OGMetadata, url string, opts Options) {
+	if len(blocks) == 0 {
+		warnf("%s: no JSON-LD data found, nothing to extract with -json-ld-only", url)
+	}
 
-func main() {
-	// Check if correct number of arguments is provided
-	if len(os.Args) != 3 {
-		printUsage()
-		os.Exit(1)
+	extractFromJSONLD(blocks, metadata, url, opts)
+
+	for _, block := range blocks {
+		nodes, _ := parseJSONLDNodes(block)
+		for _, node := range nodes {
+			if !hasJSONLDType(node, articleTypes) {
+				continue
+			}
+			if metadata.Title == "" {
+				metadata.Title, _ = node["headline"].(string)
+			}
+			if metadata.Title == "" {
+				metadata.Title, _ = node["name"].(string)
+			}
+			if metadata.Description == "" {
+				metadata.Description, _ = node["description"].(string)
+			}
+			if metadata.Source == "" {
+				metadata.Source = authorFromJSONValue(node["publisher"])
+			}
+		}
 	}
 
-	url := os.Args[1]
-	jsonFilePath := os.Args[2]
+	if metadata.Image != "" {
+		metadata.Image = resolveURL(url, metadata.Image)
+	}
+	metadata.URL = url
+}
 
-	// Fetch and extract metadata from URL
-	metadata, err := extractOGMetadata(url)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error extracting metadata: %v\n", err)
-		os.Exit(1)
+// extractOriginal fills the original URL, source and date of a syndicated article from the
+// work it is based on ("isBasedOn", a URL or a nested CreativeWork) or its "sourceOrganization",
+// so reposts can be told apart from originals
+func extractOriginal(node map[string]interface{}, metadata *OGMetadata, dateFields []string) {
+	original := node["isBasedOn"]
+	if list, ok := original.([]interface{}); ok && len(list) > 0 {
+		original = list[0]
 	}
 
-	// Create backup and append to existing JSON file
-	err = appendToJSONFile(metadata, jsonFilePath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error appending to JSON file: %v\n", err)
-		os.Exit(1)
+	switch v := original.(type) {
+	case string:
+		metadata.OriginalURL = v
+	case map[string]interface{}:
+		metadata.OriginalURL, _ = v["url"].(string)
+		if metadata.OriginalURL == "" {
+			metadata.OriginalURL, _ = v["@id"].(string)
+		}
+		metadata.OriginalSource = authorFromJSONValue(v["publisher"])
+		if metadata.OriginalSource == "" {
+			metadata.OriginalSource = authorFromJSONValue(v["sourceOrganization"])
+		}
+		for _, field := range dateFields {
+			if dateStr, ok := v[field].(string); ok && dateStr != "" {
+				metadata.OriginalDate = dateStr
+				break
+			}
+		}
 	}
 
-	// Print metadata to console
-	printMetadata(metadata)
-	fmt.Printf("\nSuccessfully appended to %s\n", jsonFilePath)
+	if metadata.OriginalSource == "" {
+		metadata.OriginalSource = authorFromJSONValue(node["sourceOrganization"])
+	}
 }
 
-func printUsage() {
-	fmt.Println("Usage: og-extractor <url> <json-file-path>")
-	fmt.Println("  url:            URL of the web page to extract Open Graph metadata from")
-	fmt.Println("  json-file-path: Path to the target JSON file to append the metadata to")
-	fmt.Println("\nThe target JSON file must follow the structure: {\"articles\":[{...}]}")
-	fmt.Println("A backup of the original file will be created before modification.")
-}
+// categoryFromBreadcrumbs returns the name of the penultimate item of a BreadcrumbList, the
+// parent of the article in the trail. A trail of only the home page and the article gives none.
+func categoryFromBreadcrumbs(node map[string]interface{}) string {
+	elements, _ := node["itemListElement"].([]interface{})
 
-func extractOGMetadata(url string) (OGMetadata, error) {
-	metadata := OGMetadata{}
-	
-	// Extract slug from URL
-	metadata.Slug = extractSlug(url)
+	type crumb struct {
+		position float64
+		name     string
+	}
+	var trail []crumb
+	for _, element := range elements {
+		item, ok := element.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := item["name"].(string)
+		// The name may be on the linked item rather than on the ListItem
+		if linked, ok := item["item"].(map[string]interface{}); ok && name == "" {
+			name, _ = linked["name"].(string)
+		}
+		trail = append(trail, crumb{jsonNumber(item["position"]), strings.TrimSpace(name)})
+	}
 
-	// Fetch the web page
-	resp, err := http.Get(url)
-	if err != nil {
-		return metadata, err
+	// Items are ordered by position, which pages don't always list in order
+	sort.SliceStable(trail, func(i, j int) bool { return trail[i].position < trail[j].position })
+
+	if len(trail) < 3 {
+		return ""
 	}
-	defer resp.Body.Close()
+	return trail[len(trail)-2].name
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return metadata, fmt.Errorf("failed to fetch URL: status code %d", resp.StatusCode)
+// isAccessibleForFree returns the isAccessibleForFree property of a node as "true" or "false",
+// accepting both booleans and strings, or an empty string when it is missing
+func isAccessibleForFree(node map[string]interface{}) string {
+	switch value := node["isAccessibleForFree"].(type) {
+	case bool:
+		return strconv.FormatBool(value)
+	case string:
+		return strings.ToLower(strings.TrimSpace(value))
 	}
+	return ""
+}
 
-	// Parse HTML
-	doc, err := html.Parse(resp.Body)
+// parseJSONLDNodes parses a JSON-LD block into its nodes, which may be a single object,
+// an array of objects, or objects listed in "@graph". Invalid JSON or a block whose "@context"
+// isn't schema.org yields no nodes and an error. Terms are normalized to their plain schema.org names.
+func parseJSONLDNodes(jsonContent string) ([]map[string]interface{}, error) {
+	var data interface{}
+	err := json.Unmarshal([]byte(sanitizeJSONLD(jsonContent)), &data)
 	if err != nil {
-		return metadata, err
+		return nil, err
 	}
+	if object, ok := data.(map[string]interface{}); ok {
+		if context, ok := object["@context"]; ok && !isSchemaOrgContext(context) {
+			return nil, fmt.Errorf("@context %v isn't schema.org", context)
+		}
+	}
+	data = normalizeJSONLD(data, nil)
 
-	// Extract Open Graph metadata
-	var extractMetadata func(*html.Node)
-	extractMetadata = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "meta" {
-			var property, content string
-			for _, attr := range n.Attr {
-				if attr.Key == "property" || attr.Key == "name" {
-					property = attr.Val
-				}
-				if attr.Key == "content" {
-					content = attr.Val
-				}
+	var nodes []map[string]interface{}
+	var collect func(interface{})
+	collect = func(value interface{}) {
+		switch v := value.(type) {
+		case []interface{}:
+			for _, item := range v {
+				collect(item)
 			}
-
-			switch property {
-			case "og:url":
-				metadata.URL = content
-			case "og:title":
-				metadata.Title = content
-			case "og:description":
-				metadata.Description = content
-			case "og:image":
-				metadata.Image = content
-			case "og:site_name":
-				metadata.Source = content
-			case "article:published_time", "datePublished", "pubdate", "publishdate", "DC.date.issued", "article:modified_time":
-				if metadata.PublishDate == "" {
-					metadata.PublishDate = content
+		case map[string]interface{}:
+			// A "@graph" wrapper only holds the actual nodes
+			if graph, ok := v["@graph"]; ok {
+				collect(graph)
+				if _, typed := v["@type"]; !typed {
+					return
 				}
 			}
+			nodes = append(nodes, v)
 		}
+	}
+	collect(data)
 
-		// Look for LD+JSON data that might contain publication date
-		if n.Type == html.ElementNode && n.Data == "script" {
-			var isJSON bool
-			for _, attr := range n.Attr {
-				if attr.Key == "type" && (attr.Val == "application/ld+json" || attr.Val == "application/json") {
-					isJSON = true
-					break
-				}
-			}
+	return nodes, nil
+}
 
-			if isJSON && n.FirstChild != nil {
-				jsonContent := n.FirstChild.Data
-				extractDateFromJSON(jsonContent, &metadata)
-			}
+// sanitizeJSONLD removes what commonly breaks the parsing of JSON-LD script text: a byte order
+// mark, HTML comment or XHTML CDATA markers around the data, invalid UTF-8 and control characters
+func sanitizeJSONLD(jsonContent string) string {
+	jsonContent = strings.TrimSpace(strings.ReplaceAll(jsonContent, "\ufeff", ""))
+	jsonContent = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(jsonContent, "<!--"), "-->"))
+	for _, marker := range []string{"//<![CDATA[", "<![CDATA["} {
+		jsonContent = strings.TrimPrefix(jsonContent, marker)
+	}
+	for _, marker := range []string{"//]]>", "]]>"} {
+		jsonContent = strings.TrimSuffix(jsonContent, marker)
+	}
+	jsonContent = strings.TrimSpace(jsonContent)
+	jsonContent = strings.ToValidUTF8(jsonContent, "")
+
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\r' || r == '\t':
+			// Allowed between tokens but not inside strings, where pages sometimes have them
+			return ' '
+		case r < 0x20 || r == 0x7f:
+			return -1
 		}
+		return r
+	}, jsonContent)
+}
 
-		// Recursively process all child nodes
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			extractMetadata(c)
+// hasJSONLDType reports whether the node's "@type", a string or an array, is one of the given types
+func hasJSONLDType(node map[string]interface{}, types map[string]bool) bool {
+	switch t := node["@type"].(type) {
+	case string:
+		return types[t]
+	case []interface{}:
+		for _, item := range t {
+			if name, ok := item.(string); ok && types[name] {
+				return true
+			}
 		}
 	}
+	return false
+}
 
-	extractMetadata(doc)
-	
-	// If we couldn't find a date in metadata, try to extract it from the URL
-	if metadata.PublishDate == "" {
-		metadata.PublishDate = extractDateFromURL(url)
+// authorFromJSONValue returns the author name(s) from a JSON-LD author value, which can be
+// a plain name, a Person object with a "name" property, or an array of either
+func authorFromJSONValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		if name, ok := v["name"].(string); ok {
+			return name
+		}
+	case []interface{}:
+		var names []string
+		for _, item := range v {
+			if name := authorFromJSONValue(item); name != "" {
+				names = append(names, name)
+			}
+		}
+		return strings.Join(names, ", ")
 	}
-	
-	return metadata, nil
+
+	return ""
 }
 
-// extractSlug extracts the slug from a URL
-func extractSlug(url string) string {
-	// Remove protocol (http://, https://)
-	cleanURL := url
-	if idx := strings.Index(cleanURL, "://"); idx != -1 {
-		cleanURL = cleanURL[idx+3:]
-	}
-	
-	// Remove query string and fragment
-	if idx := strings.Index(cleanURL, "?"); idx != -1 {
-		cleanURL = cleanURL[:idx]
-	}
-	if idx := strings.Index(cleanURL, "#"); idx != -1 {
-		cleanURL = cleanURL[:idx]
-	}
-	
-	// Remove trailing slash if present
-	cleanURL = strings.TrimSuffix(cleanURL, "/")
-	
-	// Split by slashes and take the last section
-	parts := strings.Split(cleanURL, "/")
-	if len(parts) > 0 && parts[len(parts)-1] != "" {
-		return parts[len(parts)-1]
-	} else if len(parts) > 1 {
-		// If the URL ends with a slash, take the second-to-last non-empty part
-		for i := len(parts) - 2; i >= 0; i-- {
-			if parts[i] != "" {
-				return parts[i]
+// publisherLogo returns the absolute URL of the logo of a JSON-LD publisher, an Organization whose
+// "logo" is a URL or an ImageObject. A publisher given only by its "@id" is looked up among the
+// Organization nodes of the page.
+func publisherLogo(value interface{}, organizations []map[string]interface{}, pageURL string) string {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if logo := imageFromJSONValue(v["logo"]); logo != "" {
+			return resolveURL(pageURL, logo)
+		}
+		if id, ok := v["@id"].(string); ok && id != "" {
+			for _, organization := range organizations {
+				if organization["@id"] == id {
+					return publisherLogo(organization, nil, pageURL)
+				}
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if logo := publisherLogo(item, organizations, pageURL); logo != "" {
+				return logo
 			}
 		}
 	}
-	
-	// If we can't find a valid slug, return the domain
-	domainParts := strings.Split(cleanURL, ".")
-	if len(domainParts) > 0 {
-		return domainParts[0]
-	}
-	
 	return ""
 }
 
-// extractDateFromJSON attempts to extract publication date from JSON-LD data
-func extractDateFromJSON(jsonContent string, metadata *OGMetadata) {
-	var data map[string]interface{}
-	
-	// Try to unmarshal the JSON
-	err := json.Unmarshal([]byte(jsonContent), &data)
-	if err != nil {
-		return // Ignore errors, just continue
-	}
-	
-	// Look for common date fields in schema.org and other formats
-	dateFields := []string{"datePublished", "dateCreated", "publishedTime", "dateModified", "pubDate"}
-	
-	for _, field := range dateFields {
-		if dateStr, ok := data[field].(string); ok && metadata.PublishDate == "" {
-			metadata.PublishDate = dateStr
-			return
+// imageFromJSONValue returns the image URL from a JSON-LD image value, which can be
+// a plain URL, an ImageObject with a "url" property, or an array of either
+func imageFromJSONValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		if url, ok := v["url"].(string); ok {
+			return url
 		}
-	}
-	
-	// Check for nested objects like Article type
-	if article, ok := data["@type"]; ok && (article == "Article" || article == "NewsArticle") {
-		for _, field := range dateFields {
-			if dateStr, ok := data[field].(string); ok && metadata.PublishDate == "" {
-				metadata.PublishDate = dateStr
-				return
+		if url, ok := v["contentUrl"].(string); ok {
+			return url
+		}
+	case []interface{}:
+		// Prefer the largest image with known dimensions, otherwise the first one
+		var first, largest string
+		var largestArea float64
+		for _, item := range v {
+			url := imageFromJSONValue(item)
+			if url == "" {
+				continue
+			}
+			if first == "" {
+				first = url
+			}
+			if obj, ok := item.(map[string]interface{}); ok {
+				area := jsonNumber(obj["width"]) * jsonNumber(obj["height"])
+				if area > largestArea {
+					largest = url
+					largestArea = area
+				}
 			}
 		}
+		if largest != "" {
+			return largest
+		}
+		return first
+	}
+
+	return ""
+}
+
+// jsonNumber converts a JSON-LD numeric value, which may be encoded as a string, to a float
+func jsonNumber(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return f
+		}
 	}
+	return 0
 }
 
 // extractDateFromURL attempts to find a date pattern in the URL
@@ -298,9 +2379,60 @@ func validateDate(dateStr string) bool {
 	return err == nil
 }
 
+// normalizeDate converts a date to RFC3339, returning an empty string if it can't be parsed.
+// Dates without a time zone are read in the given location.
+func normalizeDate(dateStr string, location *time.Location) string {
+	t, ok := articlesjson.ParsePublishDateIn(dateStr, location)
+	if !ok {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// loadDateOverrides reads a slug to publish date mapping from a JSON object or a two-column CSV file
+func loadDateOverrides(filePath string) (map[string]string, error) {
+	overrides := map[string]string{}
+
+	fileContent, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.ToLower(filepath.Ext(filePath)) != ".csv" {
+		err = json.Unmarshal(fileContent, &overrides)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON format: %w", err)
+		}
+		return overrides, nil
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(fileContent)).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV format: %w", err)
+	}
+	for i, record := range records {
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: expected slug and date columns", i+1)
+		}
+		// Skip an optional header row
+		if i == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "slug") {
+			continue
+		}
+		overrides[strings.TrimSpace(record[0])] = strings.TrimSpace(record[1])
+	}
+
+	return overrides, nil
+}
+
 // appendToJSONFile reads the existing JSON file, creates a backup, and appends the new metadata
-func appendToJSONFile(metadata OGMetadata, filePath string) error {
-	var collection ArticlesCollection
+func appendToJSONFile(metadata OGMetadata, filePath string, opts Options) error {
+	// Options that need the whole collection rule out splicing
+	if opts.AppendArrayOnly && !needsFullCollection(opts) {
+		appended, err := streamAppendToJSONFile(metadata, filePath, os.FileMode(opts.OutputMode), opts.Indent.Indent())
+		if err != nil || appended {
+			return err
+		}
+	}
 	
 	// Check if the file exists
 	fileInfo, err := os.Stat(filePath)
@@ -309,10 +2441,11 @@ func appendToJSONFile(metadata OGMetadata, filePath string) error {
 	}
 
 	// If file exists, read it and create a backup
+	existing := bytes.NewReader(nil)
 	if err == nil && fileInfo.Size() > 0 {
 		// Create backup with timestamp
 		backupPath := createBackupPath(filePath)
-		err = createBackupFile(filePath, backupPath)
+		err = createBackupFile(filePath, backupPath, os.FileMode(opts.OutputMode))
 		if err != nil {
 			return fmt.Errorf("failed to create backup: %w", err)
 		}
@@ -322,29 +2455,106 @@ func appendToJSONFile(metadata OGMetadata, filePath string) error {
 		if err != nil {
 			return fmt.Errorf("failed to read existing file: %w", err)
 		}
-		
-		// Parse JSON
-		err = json.Unmarshal(fileContent, &collection)
+		existing = bytes.NewReader(fileContent)
+	}
+
+	collection, err := articlesjson.ReadCollection(existing)
+	if err != nil {
+		return err
+	}
+	removed := addToCollection(&collection, metadata, opts)
+
+	// The new content is only written once the whole collection was produced
+	var output bytes.Buffer
+	err = articlesjson.WriteCollection(&output, collection, collectionOptions(opts))
+	if err != nil {
+		return err
+	}
+
+	if len(removed) > 0 && opts.ArchivePath != "" {
+		err = archiveArticles(removed, opts.ArchivePath, os.FileMode(opts.OutputMode), opts.Indent.Indent())
 		if err != nil {
-			return fmt.Errorf("invalid JSON format in existing file: %w", err)
-		}
-	} else {
-		// Initialize new collection if file doesn't exist
-		collection = ArticlesCollection{
-			Articles: []OGMetadata{},
+			return fmt.Errorf("failed to archive articles: %w", err)
 		}
 	}
 	
-	// Append new metadata to articles array
-	collection.Articles = append(collection.Articles, metadata)
-	
-	// Write back to file with indentation
-	jsonData, err := json.MarshalIndent(collection, "", "  ")
+	err = writeFileWithMode(filePath, output.Bytes(), os.FileMode(opts.OutputMode))
+	if err != nil {
+		return fmt.Errorf("failed to write to file: %w", err)
+	}
+	return nil
+}
+
+// addToCollection adds the article to the collection in memory and applies the limit,
+// returning the articles it removed
+func addToCollection(collection *ArticlesCollection, metadata OGMetadata, opts Options) []OGMetadata {
+	return collection.Add(metadata, collectionOptions(opts))
+}
+
+// collectionOptions returns the articlesjson options matching the command line flags
+func collectionOptions(opts Options) articlesjson.Options {
+	collectionOpts := articlesjson.Options{
+		Indent:            opts.Indent.Indent(),
+		Replace:           opts.Mode == "replace",
+		GroupBySource:     opts.GroupBySource,
+		Update:            opts.Update,
+		MergeTags:         opts.MergeTags,
+		MergeByTitle:      opts.MergeByTitle,
+		DisambiguateSlugs: opts.DisambiguateSlugs,
+		Limit:             opts.Limit,
+		Warnf:             warnf,
+	}
+	if !opts.Quiet {
+		collectionOpts.Printf = func(format string, args ...interface{}) { fmt.Printf(format, args...) }
+	}
+	return collectionOpts
+}
+
+// splitKeywords returns the keywords of a comma-separated string or of a JSON-LD array of them,
+// trimmed and without empty ones
+func splitKeywords(value interface{}) []string {
+	var keywords []string
+	switch v := value.(type) {
+	case string:
+		for _, keyword := range strings.Split(v, ",") {
+			if keyword = strings.TrimSpace(keyword); keyword != "" {
+				keywords = append(keywords, keyword)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			keywords = append(keywords, splitKeywords(item)...)
+		}
+	}
+	return keywords
+}
+
+// readCollection reads an articles collection from a JSON file, returning an empty collection if the file doesn't exist
+func readCollection(filePath string) (ArticlesCollection, error) {
+	collection := ArticlesCollection{
+		Articles: []OGMetadata{},
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return collection, nil
+		}
+		return collection, fmt.Errorf("failed to read existing file: %w", err)
+	}
+	defer file.Close()
+
+	return articlesjson.ReadCollection(file)
+}
+
+// writeCollection writes an articles collection to a JSON file with indentation
+func writeCollection(collection ArticlesCollection, filePath string, mode os.FileMode, indent string) error {
+	jsonData, err := marshalCollection(collection, indent)
 	if err != nil {
-		return fmt.Errorf("failed to marshal JSON: %w", err)
+		return err
 	}
 	
-	err = ioutil.WriteFile(filePath, jsonData, 0644)
+	err = writeFileWithMode(filePath, jsonData, mode)
 	if err != nil {
 		return fmt.Errorf("failed to write to file: %w", err)
 	}
@@ -352,6 +2562,81 @@ func appendToJSONFile(metadata OGMetadata, filePath string) error {
 	return nil
 }
 
+// marshalCollection formats an articles collection as it is written to the JSON file,
+// indenting each level with the given string
+func marshalCollection(collection ArticlesCollection, indent string) ([]byte, error) {
+	var jsonData bytes.Buffer
+	err := articlesjson.WriteCollection(&jsonData, collection, articlesjson.Options{Indent: indent})
+	return jsonData.Bytes(), err
+}
+
+// writeFileWithMode writes the file and, if a mode is given, sets its permissions even when
+// the file already exists. Without a mode new files get 0644 and existing ones keep theirs.
+// The data is written to <file>.og-extractor.tmp first and renamed over the file, so a crash never leaves a
+// half-written file behind (see recoverInterruptedWrite).
+func writeFileWithMode(filePath string, data []byte, mode os.FileMode) error {
+	return writeFileAtomically(filePath, mode, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// writeFileAtomically is like writeFileWithMode, for content produced by the write function
+// rather than held in memory
+func writeFileAtomically(filePath string, mode os.FileMode, write func(io.Writer) error) error {
+	// Write through a symbolic link rather than replacing it
+	if resolved, err := filepath.EvalSymlinks(filePath); err == nil {
+		filePath = resolved
+	}
+
+	perm := mode
+	if perm == 0 {
+		perm = 0644
+		if info, err := os.Stat(filePath); err == nil {
+			perm = info.Mode().Perm()
+			mode = perm
+		}
+	}
+
+	tempPath := filePath + tempFileSuffix
+	file, err := os.OpenFile(tempPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	buffered := bufio.NewWriter(file)
+	err = write(buffered)
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && mode != 0 {
+		err = os.Chmod(tempPath, mode)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	return os.Rename(tempPath, filePath)
+}
+
+// archiveArticles appends articles removed from the main collection to the archive file
+func archiveArticles(articles []OGMetadata, archivePath string, mode os.FileMode, indent string) error {
+	archive, err := readCollection(archivePath)
+	if err != nil {
+		return err
+	}
+
+	archive.Articles = append(archive.Articles, articles...)
+
+	return writeCollection(archive, archivePath, mode, indent)
+}
+
 // createBackupPath generates a backup file path with timestamp
 func createBackupPath(filePath string) string {
 	now := time.Now()
@@ -363,8 +2648,8 @@ func createBackupPath(filePath string) string {
 	return fmt.Sprintf("%s%s.%s.bkp", basePath, ext, timestamp)
 }
 
-// createBackupFile creates a backup copy of the original file
-func createBackupFile(sourcePath, destPath string) error {
+// createBackupFile creates a backup copy of the original file, with the given permissions if set
+func createBackupFile(sourcePath, destPath string, mode os.FileMode) error {
 	sourceFile, err := os.Open(sourcePath)
 	if err != nil {
 		return err
@@ -381,6 +2666,10 @@ func createBackupFile(sourcePath, destPath string) error {
 	if err != nil {
 		return err
 	}
+
+	if mode != 0 {
+		return destFile.Chmod(mode)
+	}
 	
 	return nil
 }
@@ -0,0 +1,1013 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// extractPage extracts the single article of the page at the URL
+func extractPage(t *testing.T, url string, opts Options) OGMetadata {
+	t.Helper()
+	opts.Quiet = true
+	articles, err := extractArticles(context.Background(), url, opts)
+	if err != nil {
+		t.Fatalf("extracting %s: %v", url, err)
+	}
+	if len(articles) != 1 {
+		t.Fatalf("extracted %d articles from %s, want 1", len(articles), url)
+	}
+	return articles[0]
+}
+
+// writeArticles writes a JSON file of the articles in a temporary directory and returns its path
+func writeArticles(t *testing.T, name string, articles ...OGMetadata) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if articles == nil {
+		return path
+	}
+	err := writeCollection(ArticlesCollection{Articles: articles}, path, 0, "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readArticles returns the articles of a JSON file
+func readArticles(t *testing.T, path string) []OGMetadata {
+	t.Helper()
+	collection, err := readCollection(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return collection.Articles
+}
+
+// articleSlugs returns the slugs of the articles, in order
+func articleSlugs(articles []OGMetadata) []string {
+	var slugs []string
+	for _, article := range articles {
+		slugs = append(slugs, article.Slug)
+	}
+	return slugs
+}
+
+func TestLimitArchivesOldest(t *testing.T) {
+	path := writeArticles(t, "articles.json",
+		OGMetadata{URL: "https://blog.example/first", Slug: "first", PublishDate: "2023-01-10"},
+		OGMetadata{URL: "https://blog.example/second", Slug: "second", PublishDate: "2023-06-10"},
+	)
+	archivePath := filepath.Join(filepath.Dir(path), "archive.json")
+
+	opts := Options{Quiet: true, Limit: 2, ArchivePath: archivePath}
+	opts.Indent.Set("2")
+	newest := OGMetadata{URL: "https://blog.example/third", Slug: "third", PublishDate: "2024-02-01"}
+	err := appendToJSONFile(newest, path, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := articleSlugs(readArticles(t, path)); !slices.Equal(got, []string{"third", "second"}) {
+		t.Errorf("kept articles = %v, want [third second]", got)
+	}
+	if got := articleSlugs(readArticles(t, archivePath)); !slices.Equal(got, []string{"first"}) {
+		t.Errorf("archived articles = %v, want [first]", got)
+	}
+}
+
+func TestBreadcrumbCategory(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		page string
+		want string
+	}{
+		{"breadcrumbs.html", "Engineering"},
+		{"breadcrumbs-section.html", "Performance"},
+		{"breadcrumbs-home-only.html", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			metadata := extractPage(t, server.page(tt.page), Options{})
+			if metadata.Category != tt.want {
+				t.Errorf("category = %q, want %q", metadata.Category, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyndicatedOriginal(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		page       string
+		wantURL    string
+		wantSource string
+		wantDate   string
+	}{
+		{"syndicated.html", "https://origin.example/posts/profiling", "Origin Blog", "2023-04-02"},
+		{"syndicated-url.html", "https://origin.example/posts/profiling", "Origin Blog", ""},
+		{"article.html", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			metadata := extractPage(t, server.page(tt.page), Options{})
+			if metadata.OriginalURL != tt.wantURL || metadata.OriginalSource != tt.wantSource || metadata.OriginalDate != tt.wantDate {
+				t.Errorf("original = (%q, %q, %q), want (%q, %q, %q)", metadata.OriginalURL, metadata.OriginalSource,
+					metadata.OriginalDate, tt.wantURL, tt.wantSource, tt.wantDate)
+			}
+			// The repost keeps its own date
+			if tt.wantURL != "" && metadata.PublishDate != "2023-05-15" {
+				t.Errorf("publishDate = %q, want the repost's 2023-05-15", metadata.PublishDate)
+			}
+		})
+	}
+}
+
+func TestMergedTags(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		page string
+		want []string
+	}{
+		{"keywords.html", []string{"Go", "Performance", "pprof", "Tracing", "Observability"}},
+		{"keywords-string.html", []string{"Go", "Performance", "Observability"}},
+		{"article.html", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			metadata := extractPage(t, server.page(tt.page), Options{})
+			if !slices.Equal(metadata.Tags, tt.want) {
+				t.Errorf("tags = %q, want %q", metadata.Tags, tt.want)
+			}
+		})
+	}
+}
+
+// negotiatedPage serves the localized-<language>.html fixture matching the Accept-Language header
+var negotiatedPage = fixture{Choose: func(r *http.Request) fixture {
+	if strings.HasPrefix(r.Header.Get("Accept-Language"), "de") {
+		return fixture{File: "localized-de.html"}
+	}
+	return fixture{File: "localized-en.html"}
+}}
+
+func TestAcceptLanguage(t *testing.T) {
+	server := newFixtureServer(t)
+	url := server.handle("/post", negotiatedPage)
+
+	metadata := extractPage(t, url, Options{Lang: "de-DE"})
+	requests := server.requestsTo("/post")
+	if len(requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(requests))
+	}
+	if lang := requests[0].Header.Get("Accept-Language"); lang != "de-DE" {
+		t.Errorf("Accept-Language = %q, want de-DE", lang)
+	}
+	if metadata.Title != "Go-Dienste profilieren" {
+		t.Errorf("title = %q, want the German one", metadata.Title)
+	}
+}
+
+func TestLocalizedText(t *testing.T) {
+	server := newFixtureServer(t)
+	url := server.handle("/post", negotiatedPage)
+
+	metadata := extractPage(t, url, Options{Lang: "en", Langs: stringListFlag{"en", "de"}})
+	want := map[string]LocalizedText{
+		"en": {Title: "Profiling Go Services", Description: "Finding the hot paths of a Go service."},
+		"de": {Title: "Go-Dienste profilieren", Description: "Die heißen Pfade eines Go-Dienstes finden."},
+	}
+	if !maps.Equal(metadata.Localized, want) {
+		t.Errorf("localized = %+v, want %+v", metadata.Localized, want)
+	}
+	if metadata.Title != want["en"].Title {
+		t.Errorf("title = %q, want the one of the first language", metadata.Title)
+	}
+
+	var langs []string
+	for _, r := range server.requestsTo("/post") {
+		langs = append(langs, r.Header.Get("Accept-Language"))
+	}
+	if !slices.Equal(langs, []string{"en", "de"}) {
+		t.Errorf("fetched with Accept-Language %v, want once per language", langs)
+	}
+}
+
+func TestLocalizedXHTML(t *testing.T) {
+	server := newFixtureServer(t)
+	url := server.handle("/post", fixture{Choose: func(r *http.Request) fixture {
+		if strings.HasPrefix(r.Header.Get("Accept-Language"), "de") {
+			return fixture{File: "localized-de.xhtml"}
+		}
+		return fixture{File: "localized-en.html"}
+	}})
+
+	// The self-closed script would swallow the tags after it without the XHTML handling
+	metadata := extractPage(t, url, Options{Lang: "en", Langs: stringListFlag{"en", "de"}})
+	want := LocalizedText{Title: "Go-Dienste profilieren", Description: "Die heißen Pfade eines Go-Dienstes finden."}
+	if metadata.Localized["de"] != want {
+		t.Errorf("localized de = %+v, want %+v", metadata.Localized["de"], want)
+	}
+}
+
+func TestContentLanguageLocale(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		name   string
+		file   string
+		header string
+		want   string
+	}{
+		{"from the header", "canonical-link.html", "de-DE", "de-DE"},
+		{"first of several languages", "canonical-link.html", "de-DE, en", "de-DE"},
+		{"underscore normalized", "canonical-link.html", "en_GB", "en-GB"},
+		{"no header", "canonical-link.html", "", ""},
+		{"page language preferred", "article.html", "fr", "en"},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.header != "" {
+			header.Set("Content-Language", tt.header)
+		}
+		url := server.handle("/blog/"+strings.ReplaceAll(tt.name, " ", "-"), fixture{File: tt.file, Header: header})
+
+		metadata := extractPage(t, url, Options{})
+		if metadata.Locale != tt.want {
+			t.Errorf("%s: locale = %q, want %q", tt.name, metadata.Locale, tt.want)
+		}
+	}
+}
+
+func TestStripHTML(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		stripHTML bool
+		want      string
+	}{
+		{false, "<p>Finding the <b>hot paths</b> of a Go service with <i>pprof</i>.</p>"},
+		{true, "Finding the hot paths of a Go service with pprof."},
+	}
+
+	for _, tt := range tests {
+		metadata := extractPage(t, server.page("description-markup.html"), Options{StripHTML: tt.stripHTML})
+		if metadata.Description != tt.want {
+			t.Errorf("with StripHTML %v, description = %q, want %q", tt.stripHTML, metadata.Description, tt.want)
+		}
+	}
+}
+
+func TestMetaMapping(t *testing.T) {
+	server := newFixtureServer(t)
+
+	opts := Options{MetaMapping: mappingFlag{}}
+	opts.MetaMapping.Set("blog:series=series")
+	opts.MetaMapping.Set("reading-level=level")
+	metadata := extractPage(t, server.page("custom-meta.html"), opts)
+
+	want := map[string]string{"series": "Go in Production", "level": "advanced"}
+	if !maps.Equal(metadata.Extras, want) {
+		t.Errorf("extras = %v, want %v", metadata.Extras, want)
+	}
+}
+
+func TestDateOverrides(t *testing.T) {
+	server := newFixtureServer(t)
+	url := server.handle("/blog/vibe-coding", fixture{File: "article.html"})
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"dates.json", `{"vibe-coding": "2022-11-01", "other": "2020-01-01"}`},
+		{"dates.csv", "slug,date\nother,2020-01-01\nvibe-coding,2022-11-01\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			datesPath := filepath.Join(t.TempDir(), tt.name)
+			if err := os.WriteFile(datesPath, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			overrides, err := loadDateOverrides(datesPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			output := writeArticles(t, "articles.json")
+			_, err = processURL(context.Background(), url, output, Options{Quiet: true, Mode: "append", DateOverrides: overrides})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			articles := readArticles(t, output)
+			if len(articles) != 1 || articles[0].PublishDate != "2022-11-01" {
+				t.Errorf("stored articles = %+v, want one dated 2022-11-01 instead of the extracted date", articles)
+			}
+		})
+	}
+}
+
+func TestJSONFileFormatting(t *testing.T) {
+	path := writeArticles(t, "articles.json")
+	opts := Options{Quiet: true, Mode: "append"}
+	opts.Indent.Set("2")
+
+	for _, slug := range []string{"first", "second"} {
+		err := appendToJSONFile(OGMetadata{URL: "https://blog.example/" + slug, Slug: slug}, path, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(string(data), "}\n") || strings.HasSuffix(string(data), "\n\n") {
+			t.Errorf("after appending %s, the file doesn't end with exactly one newline: %q", slug, data[max(len(data)-10, 0):])
+		}
+
+		// Writing the collection back as read doesn't change a byte
+		rewritten, err := marshalCollection(ArticlesCollection{Articles: readArticles(t, path)}, opts.Indent.Indent())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(rewritten) != string(data) {
+			t.Errorf("after appending %s, rewriting the file changes it:\n%s", slug, unifiedDiff("file", "rewritten", string(data), string(rewritten)))
+		}
+	}
+}
+
+func TestRetryDifferentUserAgent(t *testing.T) {
+	server := newFixtureServer(t)
+	url := server.handle("/blog/picky", fixture{Choose: func(r *http.Request) fixture {
+		if r.UserAgent() == "agent-b" {
+			return fixture{File: "article.html"}
+		}
+		return fixture{Status: http.StatusForbidden}
+	}})
+
+	tests := []struct {
+		name       string
+		retry      bool
+		wantErr    bool
+		wantAgents []string
+	}{
+		{"no retry", false, true, []string{"agent-a"}},
+		{"retry with another agent", true, false, []string{"agent-a", "agent-b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(server.requestsTo("/blog/picky"))
+			opts := Options{Quiet: true, UserAgent: "agent-a", RetryDifferentUA: tt.retry, AlternateUAs: stringListFlag{"agent-b"}}
+			articles, err := extractArticles(context.Background(), url, opts)
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "status code 403") {
+					t.Errorf("err = %v, want the 403", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if len(articles) != 1 || articles[0].Title != "Building a Blog with Vibe Coding" {
+				t.Errorf("articles = %+v, want the page served to agent-b", articles)
+			}
+
+			var agents []string
+			for _, r := range server.requestsTo("/blog/picky")[before:] {
+				agents = append(agents, r.UserAgent())
+			}
+			if !slices.Equal(agents, tt.wantAgents) {
+				t.Errorf("requests made as %v, want %v", agents, tt.wantAgents)
+			}
+		})
+	}
+}
+
+func TestMergeIntoExistingByTitle(t *testing.T) {
+	original := OGMetadata{URL: "https://blog.example/vibe-coding", Title: "Building a Blog with Vibe Coding", Slug: "vibe-coding"}
+	repost := OGMetadata{URL: "https://medium.example/@ada/vibe-coding-1a2b", Title: "  building a blog with  vibe coding ", Slug: "vibe-coding-1a2b"}
+
+	tests := []struct {
+		name           string
+		mergeByTitle   bool
+		wantSlugs      []string
+		wantAlternates []string
+	}{
+		{"merged", true, []string{"vibe-coding"}, []string{repost.URL}},
+		{"duplicate without the option", false, []string{"vibe-coding", "vibe-coding-1a2b"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeArticles(t, "articles.json", original)
+			err := appendToJSONFile(repost, path, Options{Quiet: true, MergeByTitle: tt.mergeByTitle})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			articles := readArticles(t, path)
+			if got := articleSlugs(articles); !slices.Equal(got, tt.wantSlugs) {
+				t.Errorf("slugs = %v, want %v", got, tt.wantSlugs)
+			}
+			if !slices.Equal(articles[0].AlternateURLs, tt.wantAlternates) {
+				t.Errorf("alternate URLs = %v, want %v", articles[0].AlternateURLs, tt.wantAlternates)
+			}
+		})
+	}
+}
+
+func TestPreferCanonicalForSlug(t *testing.T) {
+	server := newFixtureServer(t)
+	tracked := server.handle("/go/a1b2c3", fixture{File: "canonical-link.html"}) + "?utm_source=newsletter&utm_medium=email"
+	uncanonical := server.handle("/go/d4e5f6", fixture{File: "article.html"}) + "?utm_source=newsletter"
+
+	tests := []struct {
+		name   string
+		url    string
+		prefer bool
+		want   string
+	}{
+		{"canonical preferred", tracked, true, "from-the-page"},
+		{"input URL by default", tracked, false, "a1b2c3"},
+		{"no canonical to prefer", uncanonical, true, "d4e5f6"},
+	}
+	for _, tt := range tests {
+		metadata := extractPage(t, tt.url, Options{PreferCanonicalSlug: tt.prefer})
+		if metadata.Slug != tt.want {
+			t.Errorf("%s: slug = %q, want %q", tt.name, metadata.Slug, tt.want)
+		}
+	}
+}
+
+func TestNoURLDate(t *testing.T) {
+	server := newFixtureServer(t)
+	undated := server.handle("/2023/05/15/profiling/", fixture{File: "canonical-link.html"})
+	dated := server.handle("/2023/05/15/vibe-coding/", fixture{File: "article.html"})
+
+	tests := []struct {
+		name      string
+		url       string
+		noURLDate bool
+		want      string
+	}{
+		{"guessed from the URL", undated, false, "2023-05-15"},
+		{"no guess with -no-url-date", undated, true, ""},
+		{"declared date kept", dated, true, "2024-03-15T09:30:00Z"},
+	}
+	for _, tt := range tests {
+		metadata := extractPage(t, tt.url, Options{NoURLDate: tt.noURLDate})
+		if metadata.PublishDate != tt.want {
+			t.Errorf("%s: publishDate = %q, want %q", tt.name, metadata.PublishDate, tt.want)
+		}
+	}
+}
+
+func TestQueryOnSlugAndStoredURL(t *testing.T) {
+	server := newFixtureServer(t)
+	url := server.handle("/posts/vibe", fixture{Body: `<html><head>
+<meta property="og:title" content="Building a Blog with Vibe Coding">
+<meta property="og:url" content="https://blog.example/posts/vibe?p=42#intro">
+</head></html>`}) + "?p=42"
+
+	tests := []struct {
+		keepQueryInSlug bool
+		cleanURL        bool
+		wantSlug        string
+		wantURL         string
+	}{
+		{false, false, "vibe", "https://blog.example/posts/vibe?p=42#intro"},
+		{false, true, "vibe", "https://blog.example/posts/vibe"},
+		{true, false, "vibe-p-42", "https://blog.example/posts/vibe?p=42#intro"},
+		{true, true, "vibe-p-42", "https://blog.example/posts/vibe"},
+	}
+	for _, tt := range tests {
+		metadata := extractPage(t, url, Options{KeepQueryInSlug: tt.keepQueryInSlug, CleanURL: tt.cleanURL})
+		if metadata.Slug != tt.wantSlug {
+			t.Errorf("keep query in slug %v, clean URL %v: slug = %q, want %q", tt.keepQueryInSlug, tt.cleanURL, metadata.Slug, tt.wantSlug)
+		}
+		if metadata.URL != tt.wantURL {
+			t.Errorf("keep query in slug %v, clean URL %v: url = %q, want %q", tt.keepQueryInSlug, tt.cleanURL, metadata.URL, tt.wantURL)
+		}
+	}
+}
+
+func TestIndent(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", "  "},
+		{"tab", "\t"},
+		{"TAB", "\t"},
+		{"4", "    "},
+		{"0", ""},
+	}
+	for _, tt := range tests {
+		opts := Options{Quiet: true}
+		if tt.value != "" {
+			if err := opts.Indent.Set(tt.value); err != nil {
+				t.Fatalf("Set(%q): %v", tt.value, err)
+			}
+		}
+
+		path := writeArticles(t, "articles.json")
+		err := appendToJSONFile(OGMetadata{URL: "https://blog.example/first", Slug: "first"}, path, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(string(data), "\n")
+		if want := tt.want + `"articles": [`; len(lines) < 2 || lines[1] != want {
+			t.Errorf("-indent %q: second line = %q, want %q", tt.value, lines[min(1, len(lines)-1)], want)
+		}
+		if want := tt.want + tt.want + "{"; len(lines) < 3 || lines[2] != want {
+			t.Errorf("-indent %q: third line = %q, want %q", tt.value, lines[min(2, len(lines)-1)], want)
+		}
+	}
+
+	for _, value := range []string{"tabs", "-1", "9", ""} {
+		var indent indentFlag
+		if err := indent.Set(value); err == nil {
+			t.Errorf("Set(%q) accepted an invalid indentation", value)
+		}
+	}
+}
+
+func TestWordCount(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		page string
+		want int
+	}{
+		{"wordcount.html", 1250},
+		{"wordcount-string.html", 980},
+		{"wordcount-text.html", 0},
+		{"article.html", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			metadata := extractPage(t, server.page(tt.page), Options{})
+			if metadata.WordCount != tt.want {
+				t.Errorf("wordCount = %d, want %d", metadata.WordCount, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlugSegment(t *testing.T) {
+	tests := []struct {
+		url      string
+		position int
+		want     string
+		wantErr  bool
+	}{
+		{"https://example.com/blog/2023/my-post/comments", -2, "my-post", false},
+		{"https://example.com/blog/2023/my-post/comments/", -2, "my-post", false},
+		{"https://example.com/blog/2023/my-post/comments", 1, "blog", false},
+		{"https://example.com/blog/2023/my-post/comments", 0, "comments", false},
+		{"https://example.com/blog/2023/my-post/comments", -5, "", true},
+		{"https://example.com/blog", 2, "", true},
+	}
+
+	for _, tt := range tests {
+		slug, err := slugFor(tt.url, Options{SlugSegment: tt.position})
+		if (err != nil) != tt.wantErr || slug != tt.want {
+			t.Errorf("slugFor(%q, -slug-segment %d) = %q, %v, want %q (error %v)", tt.url, tt.position, slug, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestOutputPermissions(t *testing.T) {
+	existing := writeArticles(t, "existing.json", OGMetadata{URL: "https://blog.example/old", Slug: "old"})
+	if err := os.Chmod(existing, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{existing, writeArticles(t, "new.json")} {
+		opts := Options{Quiet: true, Mode: "append"}
+		opts.OutputMode.Set("0600")
+		err := appendToJSONFile(OGMetadata{URL: "https://blog.example/post", Slug: "post"}, path, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := info.Mode().Perm(); mode != 0600 {
+			t.Errorf("%s has mode %o, want 600", filepath.Base(path), mode)
+		}
+	}
+}
+
+func TestDefaultTimeZone(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		tz   string
+		want string
+	}{
+		{"", "2024-03-15T09:30:00Z"},
+		{"America/New_York", "2024-03-15T09:30:00-04:00"},
+		{"Asia/Kolkata", "2024-03-15T09:30:00+05:30"},
+	}
+
+	for _, tt := range tests {
+		opts := Options{NoURLDate: true}
+		if tt.tz != "" {
+			if err := opts.DefaultTZ.Set(tt.tz); err != nil {
+				t.Fatal(err)
+			}
+		}
+		metadata := extractPage(t, server.page("naive-time.html"), opts)
+		if metadata.PublishDate != tt.want {
+			t.Errorf("with -default-tz %q, publishDate = %q, want %q", tt.tz, metadata.PublishDate, tt.want)
+		}
+	}
+}
+
+func TestSaveHTML(t *testing.T) {
+	server := newFixtureServer(t)
+	url := server.handle("/blog/vibe-coding", fixture{File: "article.html"})
+	dir := filepath.Join(t.TempDir(), "html")
+
+	extractPage(t, url, Options{SaveHTMLDir: dir})
+
+	saved, err := os.ReadFile(filepath.Join(dir, "vibe-coding.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetched, err := os.ReadFile(filepath.Join(fixtureDir, "article.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(saved) != string(fetched) {
+		t.Error("the saved HTML differs from the fetched page")
+	}
+}
+
+func TestRelativeOGURL(t *testing.T) {
+	server := newFixtureServer(t)
+	url := server.handle("/blog/profiling-go", fixture{File: "relative-og-url.html"})
+
+	metadata := extractPage(t, url, Options{})
+	if metadata.Slug != "profiling-go" {
+		t.Errorf("slug = %q, want the one of the fetched URL", metadata.Slug)
+	}
+	if want := server.URL + "/"; metadata.URL != want {
+		t.Errorf("url = %q, want the og:url resolved to %q", metadata.URL, want)
+	}
+}
+
+func TestMergeTagsOnUpdate(t *testing.T) {
+	tests := []struct {
+		mergeTags bool
+		want      []string
+	}{
+		{false, []string{"go", "profiling"}},
+		{true, []string{"favorites", "Go", "to-read", "profiling"}},
+	}
+
+	for _, tt := range tests {
+		path := writeArticles(t, "articles.json",
+			OGMetadata{URL: "https://blog.example/post", Slug: "post", Tags: []string{"favorites", "Go", "to-read"}})
+
+		opts := Options{Quiet: true, Mode: "append", Update: true, MergeTags: tt.mergeTags}
+		err := appendToJSONFile(OGMetadata{URL: "https://blog.example/post", Slug: "post", Tags: []string{"go", "profiling"}}, path, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		articles := readArticles(t, path)
+		if len(articles) != 1 || !slices.Equal(articles[0].Tags, tt.want) {
+			t.Errorf("with MergeTags %v, articles = %+v, want one with tags %q", tt.mergeTags, articles, tt.want)
+		}
+	}
+}
+
+func TestTruncateSlug(t *testing.T) {
+	tests := []struct {
+		slug      string
+		maxLength int
+		want      string
+	}{
+		{"how-we-built-our-blog-by-describing-it-to-an-assistant-instead-of-writing-it", 40, "how-we-built-our-blog-by-describing-it"},
+		{"how-we-built-our-blog", 16, "how-we-built-our"},
+		{"how-we-built-our-blog", 17, "how-we-built-our"},
+		{"how-we-built-our-blog", 21, "how-we-built-our-blog"},
+		{"how_we.built--our-blog", 15, "how_we.built"},
+		{"supercalifragilistic-post", 10, "supercalif"},
+		{"ünïcödé-wörds-everywhere", 14, "ünïcödé-wörds"},
+	}
+	for _, tt := range tests {
+		got := truncateSlug(tt.slug, tt.maxLength)
+		if got != tt.want {
+			t.Errorf("truncateSlug(%q, %d) = %q, want %q", tt.slug, tt.maxLength, got, tt.want)
+		}
+		if n := utf8.RuneCountInString(got); n > tt.maxLength {
+			t.Errorf("truncateSlug(%q, %d) is %d characters long", tt.slug, tt.maxLength, n)
+		}
+	}
+
+	// The limit applies after the other slug options
+	server := newFixtureServer(t)
+	server.handle("/blog/how we built our blog by describing it", fixture{File: "article.html"})
+	url := server.URL + "/blog/how%20we%20built%20our%20blog%20by%20describing%20it"
+	metadata := extractPage(t, url, Options{FlattenSlugSpaces: true, MaxSlugLength: 20})
+	if metadata.Slug != "how-we-built-our" {
+		t.Errorf("slug = %q, want the flattened slug truncated", metadata.Slug)
+	}
+}
+
+func TestNormalizeWhitespace(t *testing.T) {
+	metadata := OGMetadata{
+		Title:       "  Profiling\n\tGo   Services ",
+		Description: "Finding the hot paths&nbsp;of a\r\nGo service.",
+		Author:      "\nAda   Example\n",
+		Tags:        []string{" go ", "hot\npaths"},
+		Extras:      map[string]string{"series": "Go  in\tProduction"},
+	}
+	normalizeWhitespace(&metadata)
+
+	want := OGMetadata{
+		Title:       "Profiling Go Services",
+		Description: "Finding the hot paths of a Go service.",
+		Author:      "Ada Example",
+		Tags:        []string{"go", "hot paths"},
+		Extras:      map[string]string{"series": "Go in Production"},
+	}
+	if metadata.Title != want.Title || metadata.Description != want.Description || metadata.Author != want.Author ||
+		!slices.Equal(metadata.Tags, want.Tags) || !maps.Equal(metadata.Extras, want.Extras) {
+		t.Errorf("normalized metadata = %+v, want %+v", metadata, want)
+	}
+}
+
+func TestTruncateAtWord(t *testing.T) {
+	tests := []struct {
+		text      string
+		maxLength int
+		want      string
+	}{
+		{"Übersicht über die Größenänderung von Bildern", 20, "Übersicht über die…"},
+		{"日本語の説明文がとても長い場合", 8, "日本語の説明文…"},
+		{"Go 🚀🚀🚀 rocks", 6, "Go…"},
+		{"Profiling, tracing and more", 12, "Profiling…"},
+		{"Short", 10, "Short"},
+		{"Exactly ten", 11, "Exactly ten"},
+	}
+
+	for _, tt := range tests {
+		got := truncateAtWord(tt.text, tt.maxLength)
+		if got != tt.want {
+			t.Errorf("truncateAtWord(%q, %d) = %q, want %q", tt.text, tt.maxLength, got, tt.want)
+		}
+		if !utf8.ValidString(got) || utf8.RuneCountInString(got) > tt.maxLength {
+			t.Errorf("truncateAtWord(%q, %d) = %q, not %d valid runes at most", tt.text, tt.maxLength, got, tt.maxLength)
+		}
+	}
+}
+
+func TestJSONLDImage(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		page string
+		want string
+	}{
+		{"jsonld-image-string.html", "https://blog.example/images/cover.png"},
+		{"jsonld-image-object.html", "https://blog.example/images/cover.png"},
+		// The largest image with known dimensions, even when given as strings
+		{"jsonld-image-array.html", "https://blog.example/images/cover.png"},
+		// Without dimensions, the first one
+		{"jsonld-image-array-first.html", "https://blog.example/images/cover.png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			metadata := extractPage(t, server.page(tt.page), Options{})
+			if metadata.Image != tt.want {
+				t.Errorf("image = %q, want %q", metadata.Image, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONLDArticleBlock(t *testing.T) {
+	server := newFixtureServer(t)
+
+	// The breadcrumbs and the site come before the article, with their own date, author and image
+	metadata := extractPage(t, server.page("jsonld-blocks.html"), Options{})
+	if metadata.PublishDate != "2024-05-02" {
+		t.Errorf("publishDate = %q, want the article's 2024-05-02", metadata.PublishDate)
+	}
+	if metadata.Author != "Ada Example" {
+		t.Errorf("author = %q, want the article's Ada Example", metadata.Author)
+	}
+	if metadata.Image != "https://blog.example/images/profiling.png" {
+		t.Errorf("image = %q, want the article's", metadata.Image)
+	}
+}
+
+func TestPaywalled(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		page string
+		want bool
+	}{
+		{"paywalled.html", true},
+		// The value is sometimes a string, in any case
+		{"paywalled-string.html", true},
+		{"article.html", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			metadata := extractPage(t, server.page(tt.page), Options{})
+			if metadata.Paywalled != tt.want {
+				t.Errorf("paywalled = %v, want %v", metadata.Paywalled, tt.want)
+			}
+		})
+	}
+}
+
+func TestMalformedJSONLD(t *testing.T) {
+	server := newFixtureServer(t)
+
+	// A byte order mark, and raw control characters inside a string of a commented-out block
+	for _, page := range []string{"jsonld-bom.html", "jsonld-control.html"} {
+		t.Run(page, func(t *testing.T) {
+			metadata := extractPage(t, server.page(page), Options{})
+			if metadata.PublishDate != "2024-05-02T08:00:00Z" {
+				t.Errorf("publishDate = %q, want the one of the JSON-LD block", metadata.PublishDate)
+			}
+		})
+	}
+}
+
+func TestTwitterHandles(t *testing.T) {
+	server := newFixtureServer(t)
+
+	metadata := extractPage(t, server.page("twitter-handles.html"), Options{})
+	if metadata.TwitterSite != "@exampleblog" || metadata.TwitterCreator != "@ada_example" {
+		t.Errorf("handles = (%q, %q), want (@exampleblog, @ada_example)", metadata.TwitterSite, metadata.TwitterCreator)
+	}
+
+	tests := []struct {
+		handle string
+		want   string
+	}{
+		{"@exampleblog", "@exampleblog"},
+		{" exampleblog ", "@exampleblog"},
+		{"https://twitter.com/exampleblog", "@exampleblog"},
+		{"HTTPS://www.Twitter.com/@exampleblog/", "@exampleblog"},
+		{"http://mobile.twitter.com/exampleblog", "@exampleblog"},
+		{"@", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeTwitterHandle(tt.handle); got != tt.want {
+			t.Errorf("normalizeTwitterHandle(%q) = %q, want %q", tt.handle, got, tt.want)
+		}
+	}
+}
+
+func TestStripSiteSuffix(t *testing.T) {
+	tests := []struct {
+		title    string
+		siteName string
+		want     string
+	}{
+		{"Profiling Go Services | Example Blog", "Example Blog", "Profiling Go Services"},
+		{"Profiling Go Services - Example Blog", "Example Blog", "Profiling Go Services"},
+		{"Profiling Go Services — Example Blog", "Example Blog", "Profiling Go Services"},
+		{"Profiling Go Services | example blog ", "Example Blog", "Profiling Go Services"},
+		// The suffix has to be exactly the site name
+		{"Profiling Go Services | Example Blog Archive", "Example Blog", "Profiling Go Services | Example Blog Archive"},
+		{"Profiling Go Services | Other Blog", "Example Blog", "Profiling Go Services | Other Blog"},
+		// A separator is required, so a title ending with the name is kept
+		{"Welcome to the Example Blog", "Example Blog", "Welcome to the Example Blog"},
+		{"Profiling Go Services-Example Blog", "Example Blog", "Profiling Go Services-Example Blog"},
+		// Nothing would remain of the title
+		{"Example Blog", "Example Blog", "Example Blog"},
+		{" | Example Blog", "Example Blog", " | Example Blog"},
+		{"Profiling Go Services | Example Blog", "", "Profiling Go Services | Example Blog"},
+	}
+
+	for _, tt := range tests {
+		if got := stripSiteSuffix(tt.title, tt.siteName); got != tt.want {
+			t.Errorf("stripSiteSuffix(%q, %q) = %q, want %q", tt.title, tt.siteName, got, tt.want)
+		}
+	}
+}
+
+func TestMetaValueAttribute(t *testing.T) {
+	server := newFixtureServer(t)
+
+	// The value attribute is only used when content is missing or blank
+	metadata := extractPage(t, server.page("meta-value.html"), Options{})
+	want := OGMetadata{
+		Title:       "Profiling Go Services",
+		Description: "Finding the hot paths with pprof.",
+		Source:      "Example Blog",
+		Image:       "https://blog.example/images/profiling.png",
+	}
+	got := OGMetadata{Title: metadata.Title, Description: metadata.Description, Source: metadata.Source, Image: metadata.Image}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("metadata = %+v, want %+v", got, want)
+	}
+}
+
+func TestPublisherLogo(t *testing.T) {
+	server := newFixtureServer(t)
+
+	tests := []struct {
+		page string
+		want string
+	}{
+		// Relative logos are resolved against the page, and win over the touch icon
+		{"publisher-logo.html", "/images/logo.png"},
+		{"publisher-id.html", "/blog/logo.svg"},
+		// Without a JSON-LD logo, the apple-touch-icon
+		{"touch-icon.html", "/icons/touch.png"},
+		{"article.html", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			url := server.handle("/blog/"+tt.page, fixture{File: tt.page})
+
+			want := tt.want
+			if want != "" {
+				want = server.URL + want
+			}
+			metadata := extractPage(t, url, Options{})
+			if metadata.PublisherLogo != want {
+				t.Errorf("publisherLogo = %q, want %q", metadata.PublisherLogo, want)
+			}
+		})
+	}
+}
+
+func TestJSONLDOnly(t *testing.T) {
+	server := newFixtureServer(t)
+	url := server.handle("/blog/profiling-go", fixture{File: "jsonld-conflict.html"})
+
+	tests := []struct {
+		jsonLDOnly bool
+		want       OGMetadata
+	}{
+		{false, OGMetadata{
+			Title:       "Profiling Go (updated!)",
+			Description: "A teaser written for social networks.",
+			Image:       "https://blog.example/images/social-card.png",
+			PublishDate: "2024-06-01T00:00:00Z",
+			Author:      "Example Blog Team",
+			Source:      "Example Blog Social",
+		}},
+		// Where the meta tags and the JSON-LD data disagree, the JSON-LD data wins
+		{true, OGMetadata{
+			Title:       "Profiling Go Services",
+			Description: "Finding the hot paths of a Go service with pprof.",
+			Image:       server.URL + "/images/profiling.png",
+			PublishDate: "2024-05-02T08:00:00Z",
+			Author:      "Ada Example",
+			Source:      "Example Blog",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint("json-ld-only=", tt.jsonLDOnly), func(t *testing.T) {
+			metadata := extractPage(t, url, Options{JSONLDOnly: tt.jsonLDOnly})
+			got := OGMetadata{
+				Title:       metadata.Title,
+				Description: metadata.Description,
+				Image:       metadata.Image,
+				PublishDate: metadata.PublishDate,
+				Author:      metadata.Author,
+				Source:      metadata.Source,
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("metadata = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Manifest describes a whole import job, so it can be kept under version control and repeated
+type Manifest struct {
+	Output   string                `yaml:"output"`
+	Format   string                `yaml:"format"`
+	DB       string                `yaml:"db"`
+	Defaults ArticleOverrides      `yaml:"defaults"`
+	URLs     []ManifestURL         `yaml:"urls"`
+	Required *RequiredFieldsPolicy `yaml:"required"`
+}
+
+// ManifestURL is a URL to import with the fields that override the extracted ones
+type ManifestURL struct {
+	URL              string `yaml:"url"`
+	ArticleOverrides `yaml:",inline"`
+}
+
+// ArticleOverrides are the fields of an article that a manifest can set
+type ArticleOverrides struct {
+	PublishDate string   `yaml:"publishDate"`
+	Tags        []string `yaml:"tags"`
+	Source      string   `yaml:"source"`
+}
+
+// UnmarshalYAML accepts either a plain URL or a mapping with the URL and its overrides
+func (m *ManifestURL) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		m.URL = node.Value
+		return nil
+	}
+
+	type plain ManifestURL
+	return node.Decode((*plain)(m))
+}
+
+// loadManifest reads and checks a manifest file
+func loadManifest(filePath string) (Manifest, error) {
+	var manifest Manifest
+
+	fileContent, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return manifest, err
+	}
+
+	err = yaml.Unmarshal(fileContent, &manifest)
+	if err != nil {
+		return manifest, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	if len(manifest.URLs) == 0 {
+		return manifest, fmt.Errorf("the manifest lists no URLs")
+	}
+	for i, entry := range manifest.URLs {
+		if entry.URL == "" {
+			return manifest, fmt.Errorf("URL %d of the manifest is empty", i+1)
+		}
+	}
+
+	if manifest.Required != nil {
+		err = manifest.Required.check()
+		if err != nil {
+			return manifest, fmt.Errorf("invalid required fields: %w", err)
+		}
+	}
+
+	// Relative paths are relative to the manifest, so the job runs the same from any directory
+	manifestDir := filepath.Dir(filePath)
+	for _, path := range []*string{&manifest.Output, &manifest.DB} {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(manifestDir, *path)
+		}
+	}
+
+	return manifest, nil
+}
+
+// overrides returns the overrides of each URL, falling back to the manifest defaults
+func (manifest Manifest) overrides() map[string]ArticleOverrides {
+	overrides := map[string]ArticleOverrides{}
+	for _, entry := range manifest.URLs {
+		merged := manifest.Defaults
+		if entry.PublishDate != "" {
+			merged.PublishDate = entry.PublishDate
+		}
+		if entry.Tags != nil {
+			merged.Tags = entry.Tags
+		}
+		if entry.Source != "" {
+			merged.Source = entry.Source
+		}
+		overrides[entry.URL] = merged
+	}
+	return overrides
+}
+
+// apply sets the overridden fields of the article
+func (overrides ArticleOverrides) apply(metadata *OGMetadata) {
+	if overrides.PublishDate != "" {
+		metadata.PublishDate = overrides.PublishDate
+	}
+	if overrides.Tags != nil {
+		metadata.Tags = overrides.Tags
+	}
+	if overrides.Source != "" {
+		metadata.Source = overrides.Source
+	}
+}
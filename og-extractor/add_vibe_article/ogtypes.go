@@ -0,0 +1,113 @@
+package main
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// typeExtractor fills in the fields specific to an og:type from the meta properties of the page,
+// keyed by property name with the first value found for each. It runs after the generic
+// extraction, so it can give precedence to the properties of its type.
+type typeExtractor func(properties map[string]string, metadata *OGMetadata)
+
+// typeExtractors maps og:type values to their extractor. A type also matches its subtypes,
+// so "video" handles "video.movie" and "video.episode".
+var typeExtractors = map[string]typeExtractor{
+	"video":   extractVideoType,
+	"article": extractArticleType,
+	"profile": extractProfileType,
+}
+
+// applyTypeExtractor runs the extractor registered for the og:type of the page, if any
+func applyTypeExtractor(properties map[string]string, metadata *OGMetadata) {
+	ogType := strings.ToLower(strings.TrimSpace(properties["og:type"]))
+	metadata.Type = ogType
+
+	family, _, _ := strings.Cut(ogType, ".")
+	if extractor, ok := typeExtractors[family]; ok {
+		extractor(properties, metadata)
+	}
+}
+
+// extractVideoType stores the video URL, its duration in seconds and its dimensions, and
+// prefers the release date of the video over the other dates of the page. Values that
+// aren't numbers are ignored.
+func extractVideoType(properties map[string]string, metadata *OGMetadata) {
+	for _, property := range []string{"og:video:secure_url", "og:video:url", "og:video"} {
+		if video := strings.TrimSpace(properties[property]); video != "" {
+			metadata.Video = video
+			break
+		}
+	}
+
+	for _, property := range []string{"og:video:duration", "video:duration"} {
+		if seconds := parseVideoDuration(properties[property]); seconds > 0 {
+			metadata.VideoDuration = seconds
+			break
+		}
+	}
+	metadata.VideoWidth = max(imageDimension(properties["og:video:width"]), 0)
+	metadata.VideoHeight = max(imageDimension(properties["og:video:height"]), 0)
+
+	if date := strings.TrimSpace(properties["video:release_date"]); date != "" {
+		metadata.PublishDate = date
+	}
+}
+
+// isoDurationPattern matches an ISO 8601 duration such as PT1H2M30S, as used by schema.org
+var isoDurationPattern = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// parseVideoDuration returns a duration in seconds given as a number, possibly with a
+// fraction, or in ISO 8601 format. It returns 0 for anything else.
+func parseVideoDuration(value string) int {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds <= 0 || math.IsInf(seconds, 0) || math.IsNaN(seconds) {
+			return 0
+		}
+		return int(math.Round(seconds))
+	}
+
+	match := isoDurationPattern.FindStringSubmatch(strings.ToUpper(value))
+	if match == nil {
+		return 0
+	}
+	var seconds float64
+	for i, unit := range []float64{86400, 3600, 60, 1} {
+		if part, err := strconv.ParseFloat(match[i+1], 64); err == nil {
+			seconds += part * unit
+		}
+	}
+	return int(math.Round(seconds))
+}
+
+// extractArticleType prefers article:author over the author meta tag, as the author's
+// profile URL when it is one, and article:published_time over the other dates of the page
+func extractArticleType(properties map[string]string, metadata *OGMetadata) {
+	if author := strings.TrimSpace(properties["article:author"]); author != "" {
+		if isAbsoluteURL(author) {
+			if metadata.AuthorURL == "" {
+				metadata.AuthorURL = author
+			}
+		} else {
+			metadata.Author = author
+		}
+	}
+
+	if date := strings.TrimSpace(properties["article:published_time"]); date != "" {
+		metadata.PublishDate = date
+	}
+}
+
+// extractProfileType uses the name of the person the profile page describes as the author
+func extractProfileType(properties map[string]string, metadata *OGMetadata) {
+	name := strings.TrimSpace(strings.TrimSpace(properties["profile:first_name"]) + " " + strings.TrimSpace(properties["profile:last_name"]))
+	if name == "" {
+		name = strings.TrimSpace(properties["profile:username"])
+	}
+	if name != "" {
+		metadata.Author = name
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import "testing"
+
+func TestVideoType(t *testing.T) {
+	server := newFixtureServer(t)
+
+	metadata := extractPage(t, server.page("video.html"), Options{})
+	if metadata.Type != "video.episode" {
+		t.Errorf("type = %q, want video.episode", metadata.Type)
+	}
+	if metadata.Video != "https://videos.example/profiling.mp4" {
+		t.Errorf("video = %q, want the secure URL", metadata.Video)
+	}
+	if metadata.VideoDuration != 1830 || metadata.VideoWidth != 1920 || metadata.VideoHeight != 1080 {
+		t.Errorf("video duration and size = %d, %dx%d, want 1830, 1920x1080", metadata.VideoDuration, metadata.VideoWidth, metadata.VideoHeight)
+	}
+	// The release date of the video wins over the other dates of the page
+	if metadata.PublishDate != "2024-05-10" {
+		t.Errorf("publishDate = %q, want the release date", metadata.PublishDate)
+	}
+}
+
+func TestProfileType(t *testing.T) {
+	server := newFixtureServer(t)
+
+	metadata := extractPage(t, server.page("profile.html"), Options{})
+	if metadata.Type != "profile" {
+		t.Errorf("type = %q, want profile", metadata.Type)
+	}
+	if metadata.Author != "Ada Example" {
+		t.Errorf("author = %q, want the name of the profile", metadata.Author)
+	}
+}
+
+func TestVideoNumbers(t *testing.T) {
+	server := newFixtureServer(t)
+
+	// Values that aren't numbers are ignored, falling back to the next duration property
+	metadata := extractPage(t, server.page("video-invalid.html"), Options{})
+	if metadata.VideoDuration != 1830 || metadata.VideoWidth != 0 || metadata.VideoHeight != 0 {
+		t.Errorf("video duration and size = %d, %dx%d, want 1830, 0x0", metadata.VideoDuration, metadata.VideoWidth, metadata.VideoHeight)
+	}
+
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"1830", 1830},
+		{" 1829.6 ", 1830},
+		{"PT30M30S", 1830},
+		{"pt1h", 3600},
+		{"P1DT1S", 86401},
+		{"0", 0},
+		{"-5", 0},
+		{"NaN", 0},
+		{"+Inf", 0},
+		{"30:30", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := parseVideoDuration(tt.value); got != tt.want {
+			t.Errorf("parseVideoDuration(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
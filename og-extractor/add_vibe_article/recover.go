@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// tempFileSuffix is appended to the path of a file to get the temporary file it is written to.
+// It names the tool so that only temporary files it wrote are ever removed or recovered.
+const tempFileSuffix = ".og-extractor.tmp"
+
+// recoverInterruptedWrite handles the temporary files a crash during writeFileWithMode may have
+// left next to the file and its checksum file. The work directory of an interrupted -sort
+// is not touched: running the sort again resumes from it.
+func recoverInterruptedWrite(filePath string) error {
+	for _, path := range []string{filePath, checksumPath(filePath)} {
+		if err := recoverTempFile(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recoverTempFile handles the temporary file of a single file. The file is only replaced once the
+// temporary one is complete, so when the file exists it is intact and the temporary one is removed.
+// When it doesn't, the crash came between writing and renaming a new file, which is then recovered
+// if the temporary file is complete.
+func recoverTempFile(filePath string) error {
+	tempPath := filePath + tempFileSuffix
+	if _, err := os.Stat(tempPath); err != nil {
+		return nil
+	}
+
+	if _, err := os.Stat(filePath); err == nil {
+		warnf("removing %s, left by an interrupted write; %s is intact", tempPath, filePath)
+		return os.Remove(tempPath)
+	}
+
+	if !isCompleteFile(tempPath, filePath) {
+		warnf("removing %s, an incomplete file left by an interrupted write", tempPath)
+		return os.Remove(tempPath)
+	}
+	warnf("recovering %s from %s, left by an interrupted write", filePath, tempPath)
+	err := os.Rename(tempPath, filePath)
+	if err != nil {
+		return fmt.Errorf("failed to recover %s: %w", filePath, err)
+	}
+	return nil
+}
+
+// isCompleteFile reports whether the temporary file parses in the format of the file it was
+// written for, which a file cut off during writing usually doesn't
+func isCompleteFile(tempPath, filePath string) bool {
+	content, err := ioutil.ReadFile(tempPath)
+	if err != nil || len(bytes.TrimSpace(content)) == 0 {
+		return false
+	}
+
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".csv":
+		_, err = csv.NewReader(bytes.NewReader(content)).ReadAll()
+		return err == nil && bytes.HasSuffix(content, []byte("\n"))
+	case ".yaml", ".yml":
+		var doc yaml.Node
+		return yaml.Unmarshal(content, &doc) == nil && bytes.HasSuffix(content, []byte("\n"))
+	case ".sha256":
+		fields := strings.Fields(string(content))
+		return len(fields) == 2 && len(fields[0]) == 2*sha256.Size && bytes.HasSuffix(content, []byte("\n"))
+	}
+
+	// Other files are JSON, or NDJSON with one article per line
+	if json.Valid(content) {
+		return true
+	}
+	for _, line := range bytes.Split(bytes.TrimSpace(content), []byte("\n")) {
+		if !json.Valid(line) {
+			return false
+		}
+	}
+	return bytes.HasSuffix(content, []byte("\n"))
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecoverInterruptedWrite(t *testing.T) {
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "articles.json")
+
+	// A .tmp file of the user's own is not a temporary file of the tool
+	ownTemp := filePath + ".tmp"
+	if err := os.WriteFile(ownTemp, []byte("notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// The checksum file is recovered like the file it belongs to
+	checksum := "3f786850e387550fdab836ed7e6dc881de23001b3f786850e387550fdab836ed  articles.json\n"
+	if err := os.WriteFile(checksumPath(filePath)+tempFileSuffix, []byte(checksum), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := recoverInterruptedWrite(filePath); err != nil {
+		t.Fatal(err)
+	}
+
+	if content, err := os.ReadFile(ownTemp); err != nil || string(content) != "notes" {
+		t.Errorf("the user's temp file was touched: %q, %v", content, err)
+	}
+	if content, err := os.ReadFile(checksumPath(filePath)); err != nil || string(content) != checksum {
+		t.Errorf("checksum file = %q, %v, want it recovered", content, err)
+	}
+	if _, err := os.Stat(checksumPath(filePath) + tempFileSuffix); !os.IsNotExist(err) {
+		t.Errorf("the checksum temp file is left behind: %v", err)
+	}
+}
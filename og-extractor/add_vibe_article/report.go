@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// reportedFields are the fields checked by the missing-field report, with their accessors
+var reportedFields = []struct {
+	Name  string
+	Value func(OGMetadata) string
+}{
+	{"url", func(m OGMetadata) string { return m.URL }},
+	{"title", func(m OGMetadata) string { return m.Title }},
+	{"description", func(m OGMetadata) string { return m.Description }},
+	{"image", func(m OGMetadata) string { return m.Image }},
+	{"date", func(m OGMetadata) string { return m.PublishDate }},
+	{"source", func(m OGMetadata) string { return m.Source }},
+	{"author", func(m OGMetadata) string { return m.Author }},
+}
+
+// missingFieldCount is the number of articles missing a field
+type missingFieldCount struct {
+	Field string
+	Count int
+}
+
+// countMissingFields counts, for each reported field, how many articles don't have it,
+// ordered from the most to the least commonly missing field
+func countMissingFields(articles []OGMetadata) []missingFieldCount {
+	var counts []missingFieldCount
+	for _, field := range reportedFields {
+		count := 0
+		for _, article := range articles {
+			if field.Value(article) == "" {
+				count++
+			}
+		}
+		if count > 0 {
+			counts = append(counts, missingFieldCount{Field: field.Name, Count: count})
+		}
+	}
+
+	sort.SliceStable(counts, func(i, j int) bool {
+		return counts[i].Count > counts[j].Count
+	})
+
+	return counts
+}
+
+// printMissingReport prints which fields were commonly missing in a batch of articles,
+// to help spot systemic problems with a source site
+func printMissingReport(articles []OGMetadata) {
+	counts := countMissingFields(articles)
+
+	fmt.Printf("\nMissing fields across %d article(s):\n", len(articles))
+	if len(counts) == 0 {
+		fmt.Println("  none")
+		return
+	}
+	for _, count := range counts {
+		fmt.Printf("  %d article(s) missing %s\n", count.Count, count.Field)
+	}
+}
+
+// missingFields returns the names of the reported fields that the article doesn't have
+func missingFields(article OGMetadata) []string {
+	var missing []string
+	for _, field := range reportedFields {
+		if field.Value(article) == "" {
+			missing = append(missing, field.Name)
+		}
+	}
+	return missing
+}
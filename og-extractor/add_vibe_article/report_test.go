@@ -0,0 +1,28 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCountMissingFields(t *testing.T) {
+	server := newFixtureServer(t)
+
+	var articles []OGMetadata
+	for _, page := range []string{"article.html", "title-only.html", "custom-meta.html"} {
+		articles = append(articles, extractPage(t, server.page(page), Options{NoURLDate: true}))
+	}
+
+	// None of the pages has an og:url, only article.html a description
+	want := []missingFieldCount{
+		{"url", 3},
+		{"description", 2},
+		{"image", 2},
+		{"date", 2},
+		{"source", 2},
+		{"author", 2},
+	}
+	if got := countMissingFields(articles); !slices.Equal(got, want) {
+		t.Errorf("missing fields = %v, want %v", got, want)
+	}
+}
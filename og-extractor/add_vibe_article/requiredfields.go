@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RequiredFieldsPolicy lists the fields an article must have depending on its og:type, such as a
+// video URL for videos or a publish date for articles. Fields are named as in the JSON output.
+type RequiredFieldsPolicy struct {
+	// OnMissing is "warn" (the default) to only report a missing field, or "fail" to skip the article
+	OnMissing string              `yaml:"onMissing"`
+	Types     map[string][]string `yaml:"types"`
+}
+
+// loadRequiredFieldsPolicy reads and checks a YAML policy file
+func loadRequiredFieldsPolicy(filePath string) (*RequiredFieldsPolicy, error) {
+	fileContent, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var policy RequiredFieldsPolicy
+	err = yaml.Unmarshal(fileContent, &policy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid YAML format: %w", err)
+	}
+	return &policy, policy.check()
+}
+
+// check validates the behavior on missing fields and the field names of the policy
+func (policy *RequiredFieldsPolicy) check() error {
+	switch policy.OnMissing {
+	case "":
+		policy.OnMissing = "warn"
+	case "warn", "fail":
+	default:
+		return fmt.Errorf("unknown onMissing %q, expected warn or fail", policy.OnMissing)
+	}
+
+	// og:type values are compared ignoring case
+	types := map[string][]string{}
+	for ogType, fields := range policy.Types {
+		for _, field := range fields {
+			if _, ok := metadataFieldIndex(field); !ok {
+				return fmt.Errorf("unknown field %q required for type %s", field, ogType)
+			}
+		}
+		types[strings.ToLower(ogType)] = fields
+	}
+	policy.Types = types
+	return nil
+}
+
+// missingFields returns the fields required for the og:type of the article that it doesn't have.
+// A type without its own entry falls back to its namespace, so "video" also covers "video.movie".
+func (policy *RequiredFieldsPolicy) missingFields(metadata OGMetadata) []string {
+	ogType := strings.ToLower(metadata.Type)
+	fields, ok := policy.Types[ogType]
+	if !ok {
+		namespace, _, _ := strings.Cut(ogType, ".")
+		fields = policy.Types[namespace]
+	}
+
+	value := reflect.ValueOf(metadata)
+	var missing []string
+	for _, field := range fields {
+		if i, ok := metadataFieldIndex(field); ok && value.Field(i).IsZero() {
+			missing = append(missing, field)
+		}
+	}
+	return missing
+}
+
+// metadataFieldIndex returns the index of the OGMetadata field with the given JSON name
+func metadataFieldIndex(name string) (int, bool) {
+	metadataType := reflect.TypeOf(OGMetadata{})
+	for i := 0; i < metadataType.NumField(); i++ {
+		if strings.Split(metadataType.Field(i).Tag.Get("json"), ",")[0] == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
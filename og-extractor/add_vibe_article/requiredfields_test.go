@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRequiredFieldsPerType(t *testing.T) {
+	policy := &RequiredFieldsPolicy{Types: map[string][]string{
+		"Article": {"publishDate", "author"},
+		"video":   {"video"},
+	}}
+	if err := policy.check(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		metadata OGMetadata
+		want     []string
+	}{
+		{"article missing a date", OGMetadata{Type: "article", Author: "Ada Example"}, []string{"publishDate"}},
+		{"complete article", OGMetadata{Type: "ARTICLE", Author: "Ada Example", PublishDate: "2024-03-15"}, nil},
+		{"video by namespace", OGMetadata{Type: "video.movie"}, []string{"video"}},
+		{"type without requirements", OGMetadata{Type: "website"}, nil},
+	}
+	for _, tt := range tests {
+		if got := policy.missingFields(tt.metadata); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: missing %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	invalid := &RequiredFieldsPolicy{Types: map[string][]string{"article": {"published"}}}
+	if err := invalid.check(); err == nil {
+		t.Error("a policy requiring an unknown field was accepted")
+	}
+}
+
+func TestRequiredFieldsPolicyRun(t *testing.T) {
+	server := newFixtureServer(t)
+	undated := server.handle("/blog/undated", fixture{Body: `<html><head>
+<meta property="og:title" content="Building a Blog with Vibe Coding">
+<meta property="og:type" content="article">
+</head></html>`})
+
+	tests := []struct {
+		onMissing string
+		wantCode  int
+		wantSlugs []string
+	}{
+		{"warn", 0, []string{"undated"}},
+		{"fail", 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.onMissing, func(t *testing.T) {
+			output := writeArticles(t, "articles.json")
+			policyPath := filepath.Join(filepath.Dir(output), "required.yaml")
+			policy := "onMissing: " + tt.onMissing + "\ntypes:\n  article: [publishDate]\n"
+			if err := os.WriteFile(policyPath, []byte(policy), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			result := runCLI(t, "-quiet", "-fields-required-per-type", policyPath, undated, output)
+			if result.code != tt.wantCode {
+				t.Errorf("exit code %d, want %d, stderr: %s", result.code, tt.wantCode, result.stderr)
+			}
+			if !strings.Contains(result.stderr, "undated (og:type article) is missing the required field(s) publishDate") {
+				t.Errorf("stderr doesn't report the missing date:\n%s", result.stderr)
+			}
+
+			var slugs []string
+			if _, err := os.Stat(output); err == nil {
+				slugs = articleSlugs(readArticles(t, output))
+			}
+			if !slices.Equal(slugs, tt.wantSlugs) {
+				t.Errorf("slugs = %v, want %v", slugs, tt.wantSlugs)
+			}
+		})
+	}
+}
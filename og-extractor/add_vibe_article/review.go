@@ -0,0 +1,229 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// maxDiffCells bounds the size of the table used to align the changed lines, about 20MB, beyond
+// which the changed region is shown as removed and re-added as a whole. Adding an article only
+// changes a few lines once the common leading and trailing lines are skipped, so the table
+// stays small unless the whole file is reformatted.
+const maxDiffCells = 2500000
+
+// reviewState holds the JSON file as it is on disk and as it would be written, so -review
+// can show the changes of the whole run without writing anything
+type reviewState struct {
+	original   []byte
+	collection ArticlesCollection
+}
+
+// startReview reads the JSON file that the run would modify
+func startReview(filePath string) (*reviewState, error) {
+	original, err := ioutil.ReadFile(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read existing file: %w", err)
+	}
+
+	collection, err := readCollection(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return &reviewState{original: original, collection: collection}, nil
+}
+
+// add applies an article to the proposed collection, as appendToJSONFile would
+func (review *reviewState) add(metadata OGMetadata, opts Options) {
+	if opts.Mode == "replace" {
+		review.collection = ArticlesCollection{Articles: []OGMetadata{}, Grouped: review.collection.Grouped}
+	}
+	addToCollection(&review.collection, metadata, opts)
+}
+
+// printDiff prints the proposed changes to the JSON file, written with the given indentation,
+// as a unified diff
+func (review *reviewState) printDiff(filePath, indent string) error {
+	proposed, err := marshalCollection(review.collection, indent)
+	if err != nil {
+		return err
+	}
+
+	diff := unifiedDiff("a/"+filePath, "b/"+filePath, string(review.original), string(proposed))
+	if diff == "" {
+		fmt.Println("No changes")
+		return nil
+	}
+
+	fmt.Print(diff)
+	return nil
+}
+
+// diffOp is a line of an edit script: ' ' kept, '-' removed or '+' added
+type diffOp struct {
+	kind    byte
+	line    string
+	oldLine int
+	newLine int
+}
+
+// unifiedDiff returns the line changes between two texts in unified diff format,
+// or an empty string if they are identical
+func unifiedDiff(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+
+	ops := diffLines(splitLines(oldText), splitLines(newText))
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s\n", oldName, newName)
+
+	for start := 0; start < len(ops); {
+		// Find the next change and the extent of its hunk, merging changes with close context
+		first := start
+		for first < len(ops) && ops[first].kind == ' ' {
+			first++
+		}
+		if first == len(ops) {
+			break
+		}
+
+		end := first
+		for i := first; i < len(ops); i++ {
+			if ops[i].kind != ' ' {
+				end = i + 1
+			} else if i-end >= 2*diffContext {
+				break
+			}
+		}
+
+		hunkStart := max(first-diffContext, start)
+		hunkEnd := min(end+diffContext, len(ops))
+		writeHunk(&out, ops[hunkStart:hunkEnd])
+		start = hunkEnd
+	}
+
+	return out.String()
+}
+
+// writeHunk writes the header and lines of a hunk
+func writeHunk(out *strings.Builder, ops []diffOp) {
+	var oldCount, newCount int
+	for _, op := range ops {
+		if op.kind != '+' {
+			oldCount++
+		}
+		if op.kind != '-' {
+			newCount++
+		}
+	}
+
+	// An empty range is numbered by the line before it
+	oldStart, newStart := ops[0].oldLine+1, ops[0].newLine+1
+	if oldCount == 0 {
+		oldStart--
+	}
+	if newCount == 0 {
+		newStart--
+	}
+
+	fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+	for _, op := range ops {
+		out.WriteByte(op.kind)
+		out.WriteString(op.line)
+		out.WriteByte('\n')
+	}
+}
+
+// splitLines splits a text into lines without their line breaks
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines computes an edit script turning the old lines into the new ones. Common leading and
+// trailing lines are skipped before aligning the rest by their longest common subsequence.
+func diffLines(oldLines, newLines []string) []diffOp {
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	var ops []diffOp
+	keep := func(oldIndex, newIndex int) {
+		ops = append(ops, diffOp{kind: ' ', line: oldLines[oldIndex], oldLine: oldIndex, newLine: newIndex})
+	}
+	remove := func(oldIndex, newIndex int) {
+		ops = append(ops, diffOp{kind: '-', line: oldLines[oldIndex], oldLine: oldIndex, newLine: newIndex})
+	}
+	add := func(oldIndex, newIndex int) {
+		ops = append(ops, diffOp{kind: '+', line: newLines[newIndex], oldLine: oldIndex, newLine: newIndex})
+	}
+
+	for i := 0; i < prefix; i++ {
+		keep(i, i)
+	}
+
+	oldMiddle := oldLines[prefix : len(oldLines)-suffix]
+	newMiddle := newLines[prefix : len(newLines)-suffix]
+	n, m := len(oldMiddle), len(newMiddle)
+
+	if n*m > maxDiffCells {
+		for i := 0; i < n; i++ {
+			remove(prefix+i, prefix)
+		}
+		for j := 0; j < m; j++ {
+			add(prefix+n, prefix+j)
+		}
+	} else {
+		// lcs[i][j] is the length of the longest common subsequence of oldMiddle[i:] and newMiddle[j:]
+		lcs := make([][]int, n+1)
+		for i := range lcs {
+			lcs[i] = make([]int, m+1)
+		}
+		for i := n - 1; i >= 0; i-- {
+			for j := m - 1; j >= 0; j-- {
+				if oldMiddle[i] == newMiddle[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+
+		i, j := 0, 0
+		for i < n || j < m {
+			switch {
+			case i < n && j < m && oldMiddle[i] == newMiddle[j]:
+				keep(prefix+i, prefix+j)
+				i++
+				j++
+			case j == m || (i < n && lcs[i+1][j] >= lcs[i][j+1]):
+				remove(prefix+i, prefix+j)
+				i++
+			default:
+				add(prefix+i, prefix+j)
+				j++
+			}
+		}
+	}
+
+	for k := suffix; k > 0; k-- {
+		keep(len(oldLines)-k, len(newLines)-k)
+	}
+
+	return ops
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	numbered := func(prefix string, n int) []string {
+		lines := make([]string, n)
+		for i := range lines {
+			lines[i] = fmt.Sprintf("%s %d", prefix, i)
+		}
+		return lines
+	}
+	// Every old line is kept between added ones, but aligning them would need a table larger
+	// than maxDiffCells
+	oldLarge := numbered("line", 1600)
+	var newLarge []string
+	for _, line := range oldLarge {
+		newLarge = append(newLarge, "added", line)
+	}
+	newLarge = append(newLarge, "added")
+
+	tests := []struct {
+		name     string
+		oldLines []string
+		newLines []string
+		want     string
+	}{
+		{
+			name:     "added line",
+			oldLines: []string{"{", "a", "b", "}"},
+			newLines: []string{"{", "a", "x", "b", "}"},
+			want:     "  +  ",
+		},
+		{
+			name:     "changed line",
+			oldLines: []string{"a", "b", "c", "d"},
+			newLines: []string{"a", "x", "c", "y"},
+			want:     " -+ -+",
+		},
+		{
+			name:     "past the cap",
+			oldLines: oldLarge,
+			newLines: newLarge,
+			want:     strings.Repeat("-", len(oldLarge)) + strings.Repeat("+", len(newLarge)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var kinds strings.Builder
+			for _, op := range diffLines(tt.oldLines, tt.newLines) {
+				kinds.WriteByte(op.kind)
+			}
+			if got := kinds.String(); got != tt.want {
+				if len(got) > 40 {
+					got = got[:40] + "..."
+				}
+				t.Errorf("edit script = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReviewDiff(t *testing.T) {
+	server := newFixtureServer(t)
+	url := server.handle("/blog/vibe-coding", fixture{File: "article.html"})
+	output := writeArticles(t, "articles.json", OGMetadata{URL: "https://blog.example/old", Title: "Old", Slug: "old"})
+	before, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := runCLI(t, "-review", url, output)
+	if result.code != 0 {
+		t.Fatalf("exit code %d, stderr:\n%s", result.code, result.stderr)
+	}
+
+	want := "--- a/" + output + "\n+++ b/" + output + `
+@@ -6,6 +6,18 @@
+       "description": "",
+       "image": "",
+       "slug": "old"
++    },
++    {
++      "url": "",
++      "title": "Building a Blog with Vibe Coding",
++      "description": "How we built our blog by describing it to an assistant instead of writing it.",
++      "type": "article",
++      "locale": "en",
++      "image": "https://blog.example/images/vibe.png",
++      "slug": "vibe-coding",
++      "publishDate": "2024-03-15T09:30:00Z",
++      "source": "Example Blog",
++      "author": "Ada Example"
+     }
+   ]
+ }
+`
+	if result.stdout != want {
+		t.Errorf("diff:\n%s\nwant:\n%s", result.stdout, want)
+	}
+
+	// Nothing is written
+	after, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Error("-review modified the file")
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// jsonSchemaDraft is the JSON Schema version of the emitted schema
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// articleRef points the layouts of the articles file to the schema of an article
+const articleRef = "#/$defs/article"
+
+// articlesSchema returns a JSON Schema of the articles file, which is either flat,
+// {"articles": [...]}, or grouped by source, {"sources": {"Site": [...]}}. The article schema is
+// generated from the OGMetadata struct, so it follows it as fields are added: fields without
+// omitempty are required.
+func articlesSchema() map[string]interface{} {
+	articles := map[string]interface{}{
+		"type":  "array",
+		"items": map[string]interface{}{"$ref": articleRef},
+	}
+	return map[string]interface{}{
+		"$schema": jsonSchemaDraft,
+		"title":   "Articles",
+		"oneOf": []interface{}{
+			map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"articles": articles},
+				"required":   []string{"articles"},
+			},
+			map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"sources": map[string]interface{}{
+						"type":                 "object",
+						"additionalProperties": articles,
+					},
+				},
+				"required": []string{"sources"},
+			},
+		},
+		"$defs": map[string]interface{}{
+			"article": typeSchema(reflect.TypeOf(OGMetadata{})),
+		},
+	}
+}
+
+// marshalSchema formats the schema of the articles file
+func marshalSchema() ([]byte, error) {
+	return json.MarshalIndent(articlesSchema(), "", "  ")
+}
+
+// typeSchema returns the JSON Schema of a Go type as encoding/json writes it
+func typeSchema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		properties := map[string]interface{}{}
+		required := []string{}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" || !field.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			properties[name] = typeSchema(field.Type)
+			if !strings.Contains(options, "omitempty") {
+				required = append(required, name)
+			}
+		}
+		return map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+			"required":   required,
+		}
+	}
+	return map[string]interface{}{}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestArticlesSchemaLayouts(t *testing.T) {
+	data, err := marshalSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var schema struct {
+		OneOf []struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+			Required   []string                   `json:"required"`
+		} `json:"oneOf"`
+		Defs map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	err = json.Unmarshal(data, &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var layouts []string
+	for _, layout := range schema.OneOf {
+		layouts = append(layouts, layout.Required...)
+	}
+	if !slices.Equal(layouts, []string{"articles", "sources"}) {
+		t.Errorf("layouts = %v, want [articles sources]", layouts)
+	}
+
+	article, ok := schema.Defs["article"]
+	if !ok {
+		t.Fatal("no article definition")
+	}
+	for _, field := range []string{"url", "title", "publishDate", "source"} {
+		if _, ok := article.Properties[field]; !ok {
+			t.Errorf("article schema has no %q property", field)
+		}
+	}
+}
+
+func TestArticleSchemaFields(t *testing.T) {
+	data, err := marshalSchema()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type property struct {
+		Type  string `json:"type"`
+		Items *struct {
+			Type string `json:"type"`
+		} `json:"items"`
+		AdditionalProperties *struct {
+			Type string `json:"type"`
+		} `json:"additionalProperties"`
+	}
+	var schema struct {
+		Defs struct {
+			Article struct {
+				Properties map[string]property `json:"properties"`
+				Required   []string            `json:"required"`
+			} `json:"article"`
+		} `json:"$defs"`
+	}
+	err = json.Unmarshal(data, &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	article := schema.Defs.Article
+
+	// The type of each field, with the type of its elements for arrays and maps
+	tests := []struct {
+		field    string
+		typ      string
+		elemType string
+	}{
+		{"url", "string", ""},
+		{"title", "string", ""},
+		{"description", "string", ""},
+		{"type", "string", ""},
+		{"locale", "string", ""},
+		{"image", "string", ""},
+		{"imageType", "string", ""},
+		{"imageObjects", "array", "object"},
+		{"video", "string", ""},
+		{"videoDuration", "integer", ""},
+		{"videoWidth", "integer", ""},
+		{"videoHeight", "integer", ""},
+		{"slug", "string", ""},
+		{"publishDate", "string", ""},
+		{"source", "string", ""},
+		{"publisherLogo", "string", ""},
+		{"author", "string", ""},
+		{"tags", "array", "string"},
+		{"category", "string", ""},
+		{"originalUrl", "string", ""},
+		{"originalSource", "string", ""},
+		{"originalDate", "string", ""},
+		{"canonicalUrl", "string", ""},
+		{"alternateUrls", "array", "string"},
+		{"nextUrl", "string", ""},
+		{"archivedFrom", "string", ""},
+		{"prevUrl", "string", ""},
+		{"authorUrl", "string", ""},
+		{"twitterSite", "string", ""},
+		{"twitterCreator", "string", ""},
+		{"paywalled", "boolean", ""},
+		{"wordCount", "integer", ""},
+		{"extras", "object", "string"},
+		{"localized", "object", "object"},
+		{"meta", "object", ""},
+		{"confidence", "object", "object"},
+	}
+	if len(article.Properties) != len(tests) {
+		t.Errorf("the article schema has %d properties, want %d", len(article.Properties), len(tests))
+	}
+	if fields := reflect.TypeOf(OGMetadata{}).NumField(); fields != len(tests) {
+		t.Errorf("OGMetadata has %d fields, but the test lists %d", fields, len(tests))
+	}
+
+	for _, tt := range tests {
+		prop, ok := article.Properties[tt.field]
+		if !ok {
+			t.Errorf("article schema has no %q property", tt.field)
+			continue
+		}
+		if prop.Type != tt.typ {
+			t.Errorf("%s: type = %q, want %q", tt.field, prop.Type, tt.typ)
+		}
+
+		elemType := ""
+		switch {
+		case prop.Items != nil:
+			elemType = prop.Items.Type
+		case prop.AdditionalProperties != nil:
+			elemType = prop.AdditionalProperties.Type
+		}
+		if elemType != tt.elemType {
+			t.Errorf("%s: element type = %q, want %q", tt.field, elemType, tt.elemType)
+		}
+	}
+
+	// Only the fields without omitempty are required
+	if want := []string{"url", "title", "description", "image", "slug"}; !slices.Equal(article.Required, want) {
+		t.Errorf("required = %v, want %v", article.Required, want)
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// selfTestPage is the page served locally by the self-test, {{base}} is replaced by the server URL
+const selfTestPage = `<!DOCTYPE html>
+<html>
+<head>
+  <meta property="og:url" content="{{base}}/blog/self-test-article">
+  <meta property="og:title" content="Self-Test Article">
+  <meta property="og:description" content="A page used to verify the extraction pipeline.">
+  <meta property="og:image" content="{{base}}/images/self-test.png">
+  <meta property="og:site_name" content="Self-Test Blog">
+  <script type="application/ld+json">{"@type": "Article", "datePublished": "2023-05-15"}</script>
+</head>
+<body><p>Self-test</p></body>
+</html>`
+
+// runSelfTest serves a bundled page locally, extracts its metadata and checks the expected fields,
+// so users can verify their build works without depending on an external site
+func runSelfTest(opts Options) error {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, strings.ReplaceAll(selfTestPage, "{{base}}", "http://"+r.Host))
+	}))
+	defer server.Close()
+
+	// Only the network settings apply, other options would change the expected output
+	testOpts := Options{FetchTimeout: opts.FetchTimeout}
+	articles, err := extractArticles(context.Background(), server.URL+"/blog/self-test-article", testOpts)
+	if err != nil {
+		return fmt.Errorf("extraction failed: %w", err)
+	}
+	if len(articles) != 1 {
+		return fmt.Errorf("expected 1 article, got %d", len(articles))
+	}
+	metadata := articles[0]
+
+	expected := OGMetadata{
+		URL:         server.URL + "/blog/self-test-article",
+		Title:       "Self-Test Article",
+		Description: "A page used to verify the extraction pipeline.",
+		Image:       server.URL + "/images/self-test.png",
+		Slug:        "self-test-article",
+		PublishDate: "2023-05-15",
+		Source:      "Self-Test Blog",
+	}
+
+	checks := []struct {
+		field    string
+		got      string
+		expected string
+	}{
+		{"url", metadata.URL, expected.URL},
+		{"title", metadata.Title, expected.Title},
+		{"description", metadata.Description, expected.Description},
+		{"image", metadata.Image, expected.Image},
+		{"slug", metadata.Slug, expected.Slug},
+		{"publishDate", metadata.PublishDate, expected.PublishDate},
+		{"source", metadata.Source, expected.Source},
+	}
+
+	var failures []string
+	for _, check := range checks {
+		if check.got != check.expected {
+			failures = append(failures, fmt.Sprintf("%s: got %q, expected %q", check.field, check.got, check.expected))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("unexpected metadata:\n  %s", strings.Join(failures, "\n  "))
+	}
+
+	return nil
+}
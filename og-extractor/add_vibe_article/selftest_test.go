@@ -0,0 +1,16 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	result := runCLI(t, "-selftest")
+	if result.code != 0 {
+		t.Fatalf("exit code %d, stderr:\n%s", result.code, result.stderr)
+	}
+	if !strings.Contains(result.stdout, "Self-test passed") {
+		t.Errorf("stdout = %q, want the success message", result.stdout)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	neturl "net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// siteExtractor fills in metadata from the known page structure of a site whose OG tags are
+// missing or generic. It runs after the generic extraction and only sees the parsed page.
+type siteExtractor func(doc *html.Node, metadata *OGMetadata)
+
+// siteExtractors maps host names to their extractor, new sites are supported by adding an entry.
+// A host also matches its subdomains.
+var siteExtractors = map[string]siteExtractor{
+	"docs.google.com": extractGoogleDocs,
+	"notion.site":     extractNotion,
+}
+
+// applySiteExtractor runs the extractor registered for the host of the page, if any
+func applySiteExtractor(doc *html.Node, pageURL string, metadata *OGMetadata) {
+	parsedURL, err := neturl.Parse(pageURL)
+	if err != nil {
+		return
+	}
+
+	host := strings.ToLower(parsedURL.Hostname())
+	for site, extractor := range siteExtractors {
+		if host == site || strings.HasSuffix(host, "."+site) {
+			extractor(doc, metadata)
+			return
+		}
+	}
+}
+
+// extractGoogleDocs recovers the title of a published Google Doc, which only has the generic
+// "Google Docs" og:title. The document title is in <div id="title">, or in <title>.
+func extractGoogleDocs(doc *html.Node, metadata *OGMetadata) {
+	if !isWeakTitle(metadata.Title, "Google Docs") {
+		return
+	}
+
+	if title := elementText(findElement(doc, func(n *html.Node) bool {
+		return n.Data == "div" && attrValue(n, "id") == "title"
+	})); title != "" {
+		metadata.Title = title
+		return
+	}
+
+	if title := strings.TrimSuffix(pageTitle(doc), " - Google Docs"); title != "" && !isWeakTitle(title, "Google Docs") {
+		metadata.Title = title
+	}
+}
+
+// extractNotion recovers the title of a public Notion page from its page block heading,
+// or from <title>
+func extractNotion(doc *html.Node, metadata *OGMetadata) {
+	if !isWeakTitle(metadata.Title, "Notion") {
+		return
+	}
+
+	if title := elementText(findElement(doc, func(n *html.Node) bool {
+		return n.Data == "h1" || (n.Data == "div" && strings.Contains(attrValue(n, "class"), "notion-page-block"))
+	})); title != "" {
+		metadata.Title = title
+		return
+	}
+
+	if title := strings.TrimSuffix(pageTitle(doc), " | Notion"); title != "" && !isWeakTitle(title, "Notion") {
+		metadata.Title = title
+	}
+}
+
+// isWeakTitle reports whether the title is missing or only the name of the platform
+func isWeakTitle(title, platform string) bool {
+	title = strings.TrimSpace(title)
+	return title == "" || strings.EqualFold(title, platform)
+}
+
+// pageTitle returns the text of the <title> element
+func pageTitle(doc *html.Node) string {
+	return elementText(findElement(doc, func(n *html.Node) bool {
+		return n.Data == "title"
+	}))
+}
+
+// findElement returns the first element, in document order, that matches
+func findElement(n *html.Node, match func(*html.Node) bool) *html.Node {
+	if n.Type == html.ElementNode && match(n) {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// elementText returns the text content of an element with collapsed whitespace
+func elementText(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+
+	var text strings.Builder
+	var collectText func(*html.Node)
+	collectText = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			text.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			collectText(c)
+		}
+	}
+	collectText(n)
+
+	return strings.Join(strings.Fields(text.String()), " ")
+}
+
+// attrValue returns the value of an attribute of the element, or an empty string
+func attrValue(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestSiteExtractors(t *testing.T) {
+	tests := []struct {
+		page    string
+		pageURL string
+		title   string
+		want    string
+	}{
+		{"google-docs.html", "https://docs.google.com/document/d/e/2PACX/pub", "Google Docs", "Q3 Roadmap (final)"},
+		// Without the title element, the title comes from <title>
+		{"google-docs-title.html", "https://docs.google.com/document/d/e/2PACX/pub", "Google Docs", "Q3 Roadmap"},
+		{"notion.html", "https://team.notion.site/Team-Wiki-0123", "Notion", "Team Wiki"},
+		{"notion-title.html", "https://team.notion.site/Team-Wiki-0123", "", "Team Wiki"},
+		// A real og:title is kept
+		{"google-docs.html", "https://docs.google.com/document/d/e/2PACX/pub", "Roadmap", "Roadmap"},
+		// Other sites are left alone
+		{"notion.html", "https://blog.example/posts/wiki", "Notion", "Notion"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.page, func(t *testing.T) {
+			file, err := os.Open(filepath.Join(fixtureDir, tt.page))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			doc, err := html.Parse(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			metadata := OGMetadata{Title: tt.title}
+			applySiteExtractor(doc, tt.pageURL, &metadata)
+			if metadata.Title != tt.want {
+				t.Errorf("title = %q, want %q", metadata.Title, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"add_vibe_article/articlesjson"
+)
+
+// sortCheckpointName is the file of the sort work directory recording its progress
+const sortCheckpointName = "checkpoint.json"
+
+// sortCheckpoint records how far the sort of a file went, so an interrupted sort resumes with
+// the runs already written instead of starting over
+type sortCheckpoint struct {
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"modTime"`
+	Consumed int       `json:"consumed"`
+	Runs     []string  `json:"runs"`
+}
+
+// sortFile sorts the articles of a {"articles":[...]} or NDJSON file from newest to oldest, like
+// -limit does, without holding the whole file in memory: the articles are sorted in chunks of
+// chunkSize written to temporary runs, which are then merged into the file. The runs and a
+// checkpoint are kept in <file>.sort until the sort completes, so running it again after an
+// interruption resumes where it stopped, as long as the file wasn't modified in between.
+func sortFile(filePath string, chunkSize int, opts Options) error {
+	if chunkSize < 1 {
+		return fmt.Errorf("the chunk size must be at least 1, got %d", chunkSize)
+	}
+
+	format, err := sortableFormat(filePath)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+
+	workDir := filePath + ".sort"
+	checkpoint, err := loadSortCheckpoint(workDir, info)
+	if err != nil {
+		return err
+	}
+	if checkpoint.Consumed > 0 && !opts.Quiet {
+		fmt.Printf("Resuming the sort of %s after %d article(s)\n", filePath, checkpoint.Consumed)
+	}
+
+	// Sort the articles that aren't in a run yet, one chunk at a time
+	var chunk []OGMetadata
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		articlesjson.SortByDate(chunk)
+		run := fmt.Sprintf("run-%05d.ndjson", len(checkpoint.Runs))
+		err := writeSortRun(filepath.Join(workDir, run), chunk)
+		if err != nil {
+			return err
+		}
+		checkpoint.Consumed += len(chunk)
+		checkpoint.Runs = append(checkpoint.Runs, run)
+		chunk = chunk[:0]
+		return saveSortCheckpoint(workDir, checkpoint)
+	}
+
+	skip := checkpoint.Consumed
+	err = readArticlesStream(filePath, format, func(article OGMetadata) error {
+		if skip > 0 {
+			skip--
+			return nil
+		}
+		chunk = append(chunk, article)
+		if len(chunk) < chunkSize {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return err
+	}
+	err = flush()
+	if err != nil {
+		return err
+	}
+
+	sortedPath := filepath.Join(workDir, "sorted"+filepath.Ext(filePath))
+	err = mergeSortRuns(workDir, checkpoint.Runs, sortedPath, format, opts.Indent.Indent())
+	if err != nil {
+		return err
+	}
+
+	// Create backup before replacing the file
+	backupPath := createBackupPath(filePath)
+	err = createBackupFile(filePath, backupPath, os.FileMode(opts.OutputMode))
+	if err != nil {
+		return fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	mode := os.FileMode(opts.OutputMode)
+	if mode == 0 {
+		mode = info.Mode().Perm()
+	}
+	err = os.Chmod(sortedPath, mode)
+	if err != nil {
+		return fmt.Errorf("failed to set file permissions: %w", err)
+	}
+	err = os.Rename(sortedPath, filePath)
+	if err != nil {
+		return fmt.Errorf("failed to replace %s: %w", filePath, err)
+	}
+
+	if !opts.Quiet {
+		fmt.Printf("Sorted %d article(s) of %s by date in %d run(s)\n", checkpoint.Consumed, filePath, len(checkpoint.Runs))
+	}
+	return os.RemoveAll(workDir)
+}
+
+// sortFormatPeekBytes is how much of the beginning of a file is read to recognize its format
+const sortFormatPeekBytes = 4096
+
+// sortableFormat returns "json" for an {"articles":[...]} file and "ndjson" for a file with an
+// article per line. Grouped files have to be flattened with -convert-layout first.
+func sortableFormat(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".ndjson", ".jsonl":
+		return "ndjson", nil
+	}
+
+	// A minified file is a single line, so only the beginning of the file is looked at
+	reader := bufio.NewReaderSize(file, sortFormatPeekBytes)
+	head, err := reader.Peek(sortFormatPeekBytes)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	if isArticlesArrayStart(head) {
+		return "json", nil
+	}
+
+	// Otherwise the first line should be an article, which is much smaller than a whole page
+	firstLine, err := bufio.NewReader(io.LimitReader(reader, maxResponseBytes)).ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	var article map[string]interface{}
+	if json.Unmarshal(bytes.TrimSpace(firstLine), &article) == nil {
+		return "ndjson", nil
+	}
+	return "", fmt.Errorf("only {\"articles\":[...]} and NDJSON files can be sorted (flatten grouped files with -convert-layout flat first)")
+}
+
+// readArticlesStream calls fn with each article of a {"articles":[...]} or NDJSON file in
+// order, decoding one article at a time
+func readArticlesStream(filePath, format string, fn func(OGMetadata) error) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+
+	if format == "ndjson" {
+		for lineNumber := 1; ; lineNumber++ {
+			line, err := reader.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				var article OGMetadata
+				if jsonErr := json.Unmarshal(line, &article); jsonErr != nil {
+					return fmt.Errorf("invalid JSON on line %d: %w", lineNumber, jsonErr)
+				}
+				if fnErr := fn(article); fnErr != nil {
+					return fnErr
+				}
+			}
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	decoder := json.NewDecoder(reader)
+	// Skip to the articles array, which the format check found at the start of the file
+	for i := 0; i < 3; i++ {
+		if _, err := decoder.Token(); err != nil {
+			return fmt.Errorf("invalid JSON format: %w", err)
+		}
+	}
+
+	count := 0
+	for decoder.More() {
+		var article OGMetadata
+		if err := decoder.Decode(&article); err != nil {
+			return fmt.Errorf("invalid JSON format in article %d: %w", count+1, err)
+		}
+		count++
+		if err := fn(article); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadSortCheckpoint reads the checkpoint of an interrupted sort of the file. A checkpoint
+// left by a sort of a different version of the file is discarded with its runs.
+func loadSortCheckpoint(workDir string, info os.FileInfo) (sortCheckpoint, error) {
+	fresh := sortCheckpoint{Size: info.Size(), ModTime: info.ModTime()}
+
+	content, err := os.ReadFile(filepath.Join(workDir, sortCheckpointName))
+	if err == nil {
+		var checkpoint sortCheckpoint
+		if json.Unmarshal(content, &checkpoint) == nil && checkpoint.Size == fresh.Size && checkpoint.ModTime.Equal(fresh.ModTime) {
+			return checkpoint, nil
+		}
+		warnf("discarding the interrupted sort in %s, the file changed since", workDir)
+	} else if !os.IsNotExist(err) {
+		return fresh, fmt.Errorf("failed to read the sort checkpoint: %w", err)
+	}
+
+	err = os.RemoveAll(workDir)
+	if err != nil {
+		return fresh, err
+	}
+	err = os.MkdirAll(workDir, 0755)
+	if err != nil {
+		return fresh, err
+	}
+	return fresh, nil
+}
+
+// saveSortCheckpoint records the progress of the sort, replacing the previous checkpoint at once
+// so an interruption never leaves a partial one
+func saveSortCheckpoint(workDir string, checkpoint sortCheckpoint) error {
+	content, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(workDir, sortCheckpointName)
+	err = os.WriteFile(path+".tmp", content, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write the sort checkpoint: %w", err)
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// writeSortRun writes sorted articles as NDJSON. The run only counts once the checkpoint lists
+// it, so a run interrupted while being written is simply written again.
+func writeSortRun(path string, articles []OGMetadata) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, article := range articles {
+		if err := encoder.Encode(article); err != nil {
+			return fmt.Errorf("failed to write sort run: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write sort run: %w", err)
+	}
+	return file.Close()
+}
+
+// sortRunHead is the next article of a run during the merge, with its parsed date
+type sortRunHead struct {
+	article OGMetadata
+	date    time.Time
+	dated   bool
+	run     int
+}
+
+// sortRunHeap orders the heads of the runs like articlesjson.SortByDate, breaking ties by run so
+// the merge keeps the order of the file for articles with the same date
+type sortRunHeap []sortRunHead
+
+func (h sortRunHeap) Len() int      { return len(h) }
+func (h sortRunHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h sortRunHeap) Less(i, j int) bool {
+	if h[i].dated != h[j].dated {
+		return h[i].dated
+	}
+	if !h[i].date.Equal(h[j].date) {
+		return h[i].date.After(h[j].date)
+	}
+	return h[i].run < h[j].run
+}
+func (h *sortRunHeap) Push(x interface{}) { *h = append(*h, x.(sortRunHead)) }
+func (h *sortRunHeap) Pop() interface{} {
+	old := *h
+	head := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return head
+}
+
+// mergeSortRuns merges the sorted runs into the output file, written in the format of the
+// original file with the same layout as a full rewrite
+func mergeSortRuns(workDir string, runs []string, outputPath, format, indent string) error {
+	decoders := make([]*json.Decoder, len(runs))
+	for i, run := range runs {
+		file, err := os.Open(filepath.Join(workDir, run))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		decoders[i] = json.NewDecoder(bufio.NewReader(file))
+	}
+
+	heads := &sortRunHeap{}
+	next := func(run int) error {
+		var article OGMetadata
+		err := decoders[run].Decode(&article)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read sort run %s: %w", runs[run], err)
+		}
+		date, dated := articlesjson.ParsePublishDate(article.PublishDate)
+		heap.Push(heads, sortRunHead{article: article, date: date, dated: dated, run: run})
+		return nil
+	}
+	for run := range runs {
+		if err := next(run); err != nil {
+			return err
+		}
+	}
+
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+	writer := bufio.NewWriter(output)
+
+	articleIndent := indent + indent
+	if format == "json" {
+		writer.WriteString("{\n" + indent + `"articles": [`)
+	}
+
+	for count := 0; heads.Len() > 0; count++ {
+		head := heap.Pop(heads).(sortRunHead)
+
+		var entry []byte
+		if format == "json" {
+			entry, err = json.MarshalIndent(head.article, articleIndent, indent)
+			if count > 0 {
+				writer.WriteString(",")
+			}
+			writer.WriteString("\n" + articleIndent)
+		} else {
+			entry, err = json.Marshal(head.article)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		writer.Write(entry)
+		if format == "ndjson" {
+			writer.WriteString("\n")
+		}
+
+		if err := next(head.run); err != nil {
+			return err
+		}
+	}
+
+	if format == "json" {
+		if len(runs) > 0 {
+			writer.WriteString("\n" + indent)
+		}
+		writer.WriteString("]\n}\n")
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", outputPath, err)
+	}
+	return output.Close()
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSortFile(t *testing.T) {
+	// More articles than fit in a chunk, in an order unrelated to their dates, with one undated
+	const count, chunkSize = 50, 7
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var articles []OGMetadata
+	for i := 0; i < count; i++ {
+		day := (i * 37) % count
+		articles = append(articles, OGMetadata{
+			Slug:        fmt.Sprintf("day-%02d", day),
+			PublishDate: start.AddDate(0, 0, day).Format(time.RFC3339),
+		})
+	}
+	articles = slices.Insert(articles, 20, OGMetadata{Slug: "undated"})
+
+	// Newest first, then the undated one
+	var want []string
+	for day := count - 1; day >= 0; day-- {
+		want = append(want, fmt.Sprintf("day-%02d", day))
+	}
+	want = append(want, "undated")
+
+	tests := []struct {
+		name string
+		read func(t *testing.T, path string) []OGMetadata
+	}{
+		{"articles.json", readArticles},
+		{"articles.ndjson", readNDJSONArticles},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.name)
+			writeSortInput(t, path, articles)
+
+			err := sortFile(path, chunkSize, Options{Quiet: true})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := articleSlugs(tt.read(t, path)); !slices.Equal(got, want) {
+				t.Errorf("slugs = %v, want %v", got, want)
+			}
+			if _, err := os.Stat(path + ".sort"); !os.IsNotExist(err) {
+				t.Errorf("the work directory is left behind: %v", err)
+			}
+		})
+	}
+}
+
+// writeSortInput writes the articles as a JSON collection, or one per line for an .ndjson path
+func writeSortInput(t *testing.T, path string, articles []OGMetadata) {
+	t.Helper()
+	var data []byte
+	if filepath.Ext(path) == ".ndjson" {
+		for _, article := range articles {
+			line, err := json.Marshal(article)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data = append(append(data, line...), '\n')
+		}
+	} else {
+		var err error
+		data, err = marshalCollection(ArticlesCollection{Articles: articles}, "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readNDJSONArticles reads the articles of a file with one article per line
+func readNDJSONArticles(t *testing.T, path string) []OGMetadata {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var articles []OGMetadata
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var article OGMetadata
+		if err := json.Unmarshal(scanner.Bytes(), &article); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		articles = append(articles, article)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return articles
+}
+
+func TestSortableFormat(t *testing.T) {
+	var articles []OGMetadata
+	for i := 0; i < 200; i++ {
+		articles = append(articles, OGMetadata{Slug: fmt.Sprintf("post-%03d", i), Title: fmt.Sprintf("Post %d", i)})
+	}
+	minified, err := json.Marshal(ArticlesCollection{Articles: articles})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(minified) <= sortFormatPeekBytes || slices.Contains(minified, '\n') {
+		t.Fatalf("the minified file should be a single line longer than %d bytes", sortFormatPeekBytes)
+	}
+	line, err := json.Marshal(articles[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{"minified.json", minified, "json"},
+		{"lines.txt", append(line, '\n'), "ndjson"},
+		{"array.json", []byte(`[{"slug": "a"}]`), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.name)
+			if err := os.WriteFile(path, tt.content, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := sortableFormat(path)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("format = %q, want an error", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("format = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteColumn maps an OGMetadata field to a column of the articles table
+type sqliteColumn struct {
+	Name  string
+	Type  string
+	Field int
+}
+
+// sqliteColumns derives the articles table columns from the OGMetadata JSON field names,
+// so the table follows the struct as fields are added
+func sqliteColumns() []sqliteColumn {
+	var columns []sqliteColumn
+
+	metadataType := reflect.TypeOf(OGMetadata{})
+	for i := 0; i < metadataType.NumField(); i++ {
+		field := metadataType.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		// Scalars map to native types, anything else is stored as JSON text
+		columnType := "TEXT"
+		switch field.Type.Kind() {
+		case reflect.Int, reflect.Int64, reflect.Bool:
+			columnType = "INTEGER"
+		case reflect.Float64:
+			columnType = "REAL"
+		}
+
+		columns = append(columns, sqliteColumn{Name: name, Type: columnType, Field: i})
+	}
+
+	return columns
+}
+
+// insertIntoSQLite inserts the metadata into the articles table of the database,
+// creating the table if needed and replacing any existing row with the same slug
+func insertIntoSQLite(metadata OGMetadata, dbPath string) error {
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
+
+	columns := sqliteColumns()
+	err = ensureArticlesTable(db, columns)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	values := make([]interface{}, len(columns))
+	metadataValue := reflect.ValueOf(metadata)
+	for i, column := range columns {
+		names[i] = quoteIdentifier(column.Name)
+		placeholders[i] = "?"
+		values[i], err = sqliteValue(metadataValue.Field(column.Field))
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", column.Name, err)
+		}
+	}
+
+	query := fmt.Sprintf("INSERT OR REPLACE INTO articles (%s) VALUES (%s)",
+		strings.Join(names, ", "), strings.Join(placeholders, ", "))
+	_, err = db.Exec(query, values...)
+	if err != nil {
+		return fmt.Errorf("failed to insert article: %w", err)
+	}
+
+	return nil
+}
+
+// ensureArticlesTable creates the articles table, or adds the columns missing from an existing one
+func ensureArticlesTable(db *sql.DB, columns []sqliteColumn) error {
+	definitions := make([]string, len(columns))
+	for i, column := range columns {
+		definitions[i] = quoteIdentifier(column.Name) + " " + column.Type
+		if column.Name == "slug" {
+			definitions[i] += " PRIMARY KEY"
+		}
+	}
+
+	_, err := db.Exec(fmt.Sprintf("CREATE TABLE IF NOT EXISTS articles (%s)", strings.Join(definitions, ", ")))
+	if err != nil {
+		return fmt.Errorf("failed to create articles table: %w", err)
+	}
+
+	rows, err := db.Query("PRAGMA table_info(articles)")
+	if err != nil {
+		return fmt.Errorf("failed to read articles table: %w", err)
+	}
+	existing := map[string]bool{}
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultValue sql.NullString
+		err = rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read articles table: %w", err)
+		}
+		existing[name] = true
+	}
+	rows.Close()
+
+	for _, column := range columns {
+		if existing[column.Name] {
+			continue
+		}
+		_, err = db.Exec(fmt.Sprintf("ALTER TABLE articles ADD COLUMN %s %s", quoteIdentifier(column.Name), column.Type))
+		if err != nil {
+			return fmt.Errorf("failed to add column %s: %w", column.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// sqliteValue converts a struct field to a value that can be bound to a statement
+func sqliteValue(value reflect.Value) (interface{}, error) {
+	switch value.Kind() {
+	case reflect.String:
+		return value.String(), nil
+	case reflect.Int, reflect.Int64:
+		return value.Int(), nil
+	case reflect.Bool:
+		return value.Bool(), nil
+	case reflect.Float64:
+		return value.Float(), nil
+	}
+
+	if value.IsZero() {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(value.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// quoteIdentifier quotes a column name for use in SQL statements
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
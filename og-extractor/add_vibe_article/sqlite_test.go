@@ -0,0 +1,41 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestInsertIntoSQLiteReplaces(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "articles.db")
+	article := OGMetadata{URL: "https://blog.example/post", Title: "First title", Slug: "post", Tags: []string{"go"}}
+
+	err := insertIntoSQLite(article, dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	article.Title = "Updated title"
+	err = insertIntoSQLite(article, dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var count int
+	var title, tags string
+	err = db.QueryRow("SELECT COUNT(*), MAX(title), MAX(tags) FROM articles").Scan(&count, &title, &tags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("got %d rows, want 1", count)
+	}
+	if title != "Updated title" || tags != `["go"]` {
+		t.Errorf("row = (%q, %q), want the re-inserted article", title, tags)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// needsFullCollection reports whether adding an article depends on the existing articles or
+// rewrites the file's layout, which rules out splicing it in with -append-array-only. Every
+// option handled by addToCollection besides plain appending belongs here.
+func needsFullCollection(opts Options) bool {
+	return opts.Limit > 0 || opts.Update || opts.MergeByTitle || opts.Mode == "replace" ||
+		opts.GroupBySource || opts.DisambiguateSlugs
+}
+
+// streamAppendToJSONFile appends the metadata to a {"articles":[...]} file by splicing it in
+// after the last article, without decoding the existing articles into a collection. It returns
+// false when the file structure can't be recognized with certainty, so the caller can fall back
+// to a full rewrite. Like every other write, the new file is written next to the old one and
+// renamed over it, so a crash can't leave it half-written.
+func streamAppendToJSONFile(metadata OGMetadata, filePath string, mode os.FileMode, indent string) (bool, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	// Check that the file starts with the articles array before scanning it
+	head := make([]byte, 64)
+	n, err := file.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	if !isArticlesArrayStart(head[:n]) {
+		return false, nil
+	}
+
+	end, empty, ok := articlesArrayEnd(file)
+	if !ok {
+		return false, nil
+	}
+
+	// Produce the same layout as the full rewrite: articles indented by two levels
+	articleIndent := indent + indent
+	separator := ",\n" + articleIndent
+	if empty {
+		separator = "\n" + articleIndent
+	}
+
+	entry, err := json.MarshalIndent(metadata, articleIndent, indent)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	// Create backup before replacing the file
+	backupPath := createBackupPath(filePath)
+	err = createBackupFile(filePath, backupPath, mode)
+	if err != nil {
+		return false, fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	// The file up to the end of the last article is copied as is, followed by the new article
+	err = writeFileAtomically(filePath, mode, func(w io.Writer) error {
+		_, err := file.Seek(0, io.SeekStart)
+		if err != nil {
+			return err
+		}
+		_, err = io.CopyN(w, file, end)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s%s\n%s]\n}\n", separator, entry, indent)
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to write to file: %w", err)
+	}
+	return true, nil
+}
+
+// articlesArrayEnd scans a file holding only {"articles":[...]}, one article at a time, and
+// returns the offset right after the last article, or after the opening bracket when the array
+// is empty. It isn't ok when the array holds anything but objects or isn't the only key of the
+// file, since a closing bracket at the end of the file may then belong to another array.
+func articlesArrayEnd(r io.Reader) (end int64, empty bool, ok bool) {
+	decoder := json.NewDecoder(r)
+	for _, want := range []json.Token{json.Delim('{'), "articles", json.Delim('[')} {
+		token, err := decoder.Token()
+		if err != nil || token != want {
+			return 0, false, false
+		}
+	}
+
+	end, empty = decoder.InputOffset(), true
+	for decoder.More() {
+		var article json.RawMessage
+		err := decoder.Decode(&article)
+		if err != nil || article[0] != '{' {
+			return 0, false, false
+		}
+		end, empty = decoder.InputOffset(), false
+	}
+
+	for _, want := range []json.Token{json.Delim(']'), json.Delim('}')} {
+		token, err := decoder.Token()
+		if err != nil || token != want {
+			return 0, false, false
+		}
+	}
+	_, err := decoder.Token()
+	return end, empty, err == io.EOF
+}
+
+// isArticlesArrayStart reports whether the data begins with `{"articles": [`, allowing whitespace
+func isArticlesArrayStart(data []byte) bool {
+	for _, token := range []string{"{", `"articles"`, ":", "["} {
+		data = bytes.TrimLeft(data, " \t\r\n")
+		if !bytes.HasPrefix(data, []byte(token)) {
+			return false
+		}
+		data = data[len(token):]
+	}
+	return true
+}
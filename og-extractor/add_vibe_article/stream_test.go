@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAppendArrayOnlyNeedsFullCollection(t *testing.T) {
+	existing := `{
+  "articles": [
+    {"url": "https://a.example/blog/post", "title": "A", "description": "", "image": "", "slug": "post", "source": "A"}
+  ]
+}
+`
+	article := OGMetadata{URL: "https://b.example/blog/post", Title: "B", Slug: "post", Source: "B"}
+
+	tests := []struct {
+		name  string
+		opts  Options
+		check func(t *testing.T, collection ArticlesCollection)
+	}{
+		{
+			name: "plain splice",
+			opts: Options{},
+			check: func(t *testing.T, collection ArticlesCollection) {
+				if len(collection.Articles) != 2 || collection.Grouped {
+					t.Errorf("got %d articles, grouped %v, want 2 flat articles", len(collection.Articles), collection.Grouped)
+				}
+			},
+		},
+		{
+			name: "group by source",
+			opts: Options{GroupBySource: true},
+			check: func(t *testing.T, collection ArticlesCollection) {
+				if !collection.Grouped {
+					t.Error("the file was left flat")
+				}
+			},
+		},
+		{
+			name: "disambiguate slugs",
+			opts: Options{DisambiguateSlugs: true},
+			check: func(t *testing.T, collection ArticlesCollection) {
+				if len(collection.Articles) != 2 || collection.Articles[1].Slug == "post" {
+					t.Errorf("the new article kept the slug of the existing one: %+v", collection.Articles)
+				}
+			},
+		},
+		{
+			name: "limit",
+			opts: Options{Limit: 1},
+			check: func(t *testing.T, collection ArticlesCollection) {
+				if len(collection.Articles) != 1 {
+					t.Errorf("got %d articles, want 1", len(collection.Articles))
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "articles.json")
+			if err := os.WriteFile(filePath, []byte(existing), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			tt.opts.AppendArrayOnly = true
+			tt.opts.Mode = "append"
+			if err := appendToJSONFile(article, filePath, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+
+			collection, err := readCollection(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.check(t, collection)
+		})
+	}
+}
+
+func TestStreamAppendMatchesRewrite(t *testing.T) {
+	var articles []OGMetadata
+	for i := range 5000 {
+		articles = append(articles, OGMetadata{
+			URL:   fmt.Sprintf("https://blog.example/posts/%d", i),
+			Title: fmt.Sprintf("Post %d", i),
+			Slug:  fmt.Sprint(i),
+		})
+	}
+	article := OGMetadata{URL: "https://blog.example/posts/new", Title: "New", Slug: "new", Tags: []string{"go"}}
+
+	tests := []struct {
+		name     string
+		articles []OGMetadata
+		indent   string
+	}{
+		{"large file", articles, "2"},
+		{"tab indent", articles[:3], "tab"},
+		{"empty array", []OGMetadata{}, "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts Options
+			opts.Quiet = true
+			opts.Mode = "append"
+			opts.Indent.Set(tt.indent)
+
+			dir := t.TempDir()
+			var files [2][]byte
+			for i, appendArrayOnly := range []bool{false, true} {
+				filePath := filepath.Join(dir, fmt.Sprintf("articles-%d.json", i))
+				err := writeCollection(ArticlesCollection{Articles: tt.articles}, filePath, 0, opts.Indent.Indent())
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if appendArrayOnly {
+					appended, err := streamAppendToJSONFile(article, filePath, 0, opts.Indent.Indent())
+					if err != nil || !appended {
+						t.Fatalf("splicing: appended %v, err %v", appended, err)
+					}
+				} else if err := appendToJSONFile(article, filePath, opts); err != nil {
+					t.Fatal(err)
+				}
+				if files[i], err = os.ReadFile(filePath); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if !bytes.Equal(files[0], files[1]) {
+				t.Errorf("the spliced file differs from the rewritten one:\n%s", unifiedDiff("rewrite", "splice", string(files[0]), string(files[1])))
+			}
+		})
+	}
+}
+
+func TestStreamAppendAmbiguousStructure(t *testing.T) {
+	article := OGMetadata{URL: "https://blog.example/posts/new", Title: "New", Slug: "new"}
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"another array last", `{"articles": [{"slug": "a"}], "drafts": [{"slug": "b"}]}`},
+		{"another key last", `{"articles": [{"slug": "a"}], "version": 2}`},
+		{"non-object article", `{"articles": [{"slug": "a"}, "b"]}`},
+		{"trailing data", `{"articles": [{"slug": "a"}]} {"articles": []}`},
+		{"truncated", `{"articles": [{"slug": "a"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath := filepath.Join(t.TempDir(), "articles.json")
+			if err := os.WriteFile(filePath, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			appended, err := streamAppendToJSONFile(article, filePath, 0, "  ")
+			if err != nil || appended {
+				t.Fatalf("appended %v, err %v, want a fall back to the full rewrite", appended, err)
+			}
+			data, err := os.ReadFile(filePath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.content {
+				t.Errorf("the file was modified:\n%s", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// tlsVersions are the TLS versions accepted by -tls-min
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsVersionFlag is a minimum TLS version flag, such as 1.2
+type tlsVersionFlag uint16
+
+func (v *tlsVersionFlag) String() string {
+	for name, version := range tlsVersions {
+		if uint16(*v) == version {
+			return name
+		}
+	}
+	return ""
+}
+
+func (v *tlsVersionFlag) Set(value string) error {
+	version, ok := tlsVersions[value]
+	if !ok {
+		return fmt.Errorf("unknown TLS version %q, expected 1.0, 1.1, 1.2 or 1.3", value)
+	}
+	*v = tlsVersionFlag(version)
+	return nil
+}
+
+// cipherSuitesFlag is a comma-separated list of TLS cipher suite names, as in crypto/tls,
+// such as TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+type cipherSuitesFlag []uint16
+
+func (c *cipherSuitesFlag) String() string {
+	var names []string
+	for _, id := range *c {
+		names = append(names, tls.CipherSuiteName(id))
+	}
+	return strings.Join(names, ",")
+}
+
+func (c *cipherSuitesFlag) Set(value string) error {
+	suites := append(tls.CipherSuites(), tls.InsecureCipherSuites()...)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		i := 0
+		for i < len(suites) && suites[i].Name != name {
+			i++
+		}
+		if i == len(suites) {
+			return fmt.Errorf("unknown cipher suite %q", name)
+		}
+		*c = append(*c, suites[i].ID)
+	}
+	return nil
+}
+
+// tlsTransport returns the transport enforcing the TLS policy of -tls-min and -tls-ciphers,
+// or nil for the default transport when there is no policy
+func tlsTransport(opts Options) http.RoundTripper {
+	if opts.TLSMinVersion == 0 && len(opts.TLSCiphers) == 0 {
+		return nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		MinVersion:   uint16(opts.TLSMinVersion),
+		CipherSuites: opts.TLSCiphers,
+	}
+	return transport
+}
+
+// tlsPolicyError explains a failed TLS handshake when a TLS policy is set, since the server
+// may just not meet it
+func tlsPolicyError(err error, opts Options) error {
+	if opts.TLSMinVersion == 0 && len(opts.TLSCiphers) == 0 {
+		return err
+	}
+
+	// A certificate the server presents is checked whatever the policy
+	var certErr *tls.CertificateVerificationError
+	if errors.As(err, &certErr) {
+		return err
+	}
+
+	// The client refuses a version or cipher suite below the policy with an error of its own,
+	// while a server unable to meet it ends the handshake with an alert
+	var alert tls.AlertError
+	if errors.As(err, &alert) || strings.Contains(err.Error(), "tls: ") {
+		var policy []string
+		if opts.TLSMinVersion != 0 {
+			policy = append(policy, "-tls-min "+opts.TLSMinVersion.String())
+		}
+		if len(opts.TLSCiphers) > 0 {
+			policy = append(policy, "-tls-ciphers "+opts.TLSCiphers.String())
+		}
+		return fmt.Errorf("TLS connection refused, the server doesn't meet the TLS policy (%s): %w",
+			strings.Join(policy, ", "), err)
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTLSMinVersion(t *testing.T) {
+	page, err := os.ReadFile(filepath.Join(fixtureDir, "article.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name             string
+		serverMaxVersion uint16
+		tlsMin           string
+		wantErr          bool
+	}{
+		{"TLS 1.1 server below -tls-min 1.2", tls.VersionTLS11, "1.2", true},
+		{"TLS 1.2 server below -tls-min 1.3", tls.VersionTLS12, "1.3", true},
+		{"TLS 1.3 server meeting -tls-min 1.2", tls.VersionTLS13, "1.2", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.Write(page)
+			}))
+			server.TLS = &tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: tt.serverMaxVersion}
+			// The refused handshakes are expected
+			server.Config.ErrorLog = log.New(io.Discard, "", 0)
+			server.StartTLS()
+			defer server.Close()
+
+			opts := Options{Quiet: true}
+			if err := opts.TLSMinVersion.Set(tt.tlsMin); err != nil {
+				t.Fatal(err)
+			}
+			// Trust the certificate of the test server, keeping the TLS policy of the options
+			transport := tlsTransport(opts).(*http.Transport)
+			transport.TLSClientConfig.RootCAs = x509.NewCertPool()
+			transport.TLSClientConfig.RootCAs.AddCert(server.Certificate())
+			opts.Transport = transport
+
+			articles, err := extractArticles(context.Background(), server.URL+"/blog/secure", opts)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(articles) != 1 || articles[0].Title != "Building a Blog with Vibe Coding" {
+					t.Errorf("articles = %+v, want the page", articles)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatal("the connection to a server below the minimum TLS version succeeded")
+			}
+			want := "TLS connection refused, the server doesn't meet the TLS policy (-tls-min " + tt.tlsMin + ")"
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("err = %v, want it to contain %q", err, want)
+			}
+		})
+	}
+}
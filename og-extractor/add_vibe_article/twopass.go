@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// crawlerUserAgent and crawlerAccept mimic the crawler of a social network fetching a link
+// preview, which many sites serve their full Open Graph tags to even when browsers get a
+// page rendered by JavaScript
+const (
+	crawlerUserAgent = "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)"
+	crawlerAccept    = "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8"
+)
+
+// Passes recorded in the extraction details by -two-pass
+const (
+	passNormal  = "normal"
+	passCrawler = "crawler"
+)
+
+// extractTwoPass extracts the page normally and, when its Open Graph data is sparse, fetches it
+// again as a social network crawler, keeping the second result when it is richer. The pass that
+// produced the data is recorded in the extraction details of each article.
+func extractTwoPass(ctx context.Context, url string, opts Options) ([]OGMetadata, error) {
+	articles, err := extractArticlesFrom(ctx, url, url, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	pass := passNormal
+	if len(articles) == 1 && ogRichness(articles[0]) < 3 {
+		crawlerOpts := opts
+		crawlerOpts.UserAgent = crawlerUserAgent
+		crawlerOpts.Accept = crawlerAccept
+
+		if !opts.Quiet {
+			fmt.Printf("Sparse Open Graph data for %s, fetching it again as a crawler\n", url)
+		}
+		crawled, err := extractArticlesFrom(ctx, url, url, crawlerOpts)
+		switch {
+		case err != nil:
+			warnf("second pass for %s failed: %v", url, err)
+		case len(crawled) == 1 && ogRichness(crawled[0]) > ogRichness(articles[0]):
+			articles, pass = crawled, passCrawler
+		}
+	}
+
+	for i := range articles {
+		if articles[i].Meta == nil {
+			articles[i].Meta = &ExtractionMeta{FetchedAt: time.Now().UTC().Format(time.RFC3339), Lang: opts.Lang}
+		}
+		articles[i].Meta.Pass = pass
+	}
+	return articles, nil
+}
+
+// ogRichness counts the main Open Graph fields the article has: title, description and image
+func ogRichness(metadata OGMetadata) int {
+	count := 0
+	for _, value := range []string{metadata.Title, metadata.Description, metadata.Image} {
+		if value != "" {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTwoPass(t *testing.T) {
+	server := newFixtureServer(t)
+	shell := fixture{Body: `<html><head><title>Loading</title></head><body><div id="app"></div></body></html>`}
+	crawlerOnly := server.handle("/blog/crawler-only", fixture{Choose: func(r *http.Request) fixture {
+		if strings.HasPrefix(r.UserAgent(), "facebookexternalhit/") {
+			return fixture{File: "article.html"}
+		}
+		return shell
+	}})
+	rich := server.handle("/blog/rich", fixture{File: "article.html"})
+
+	tests := []struct {
+		name            string
+		url             string
+		twoPass         bool
+		wantDescription bool
+		wantPass        string
+		wantAgents      []string
+	}{
+		{"crawler pass used", crawlerOnly, true, true, passCrawler, []string{"og-extractor", crawlerUserAgent}},
+		{"single pass by default", crawlerOnly, false, false, "", []string{"og-extractor"}},
+		{"rich first pass", rich, true, true, passNormal, []string{"og-extractor"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := strings.TrimPrefix(tt.url, server.URL)
+			before := len(server.requestsTo(path))
+
+			metadata := extractPage(t, tt.url, Options{UserAgent: "og-extractor", TwoPass: tt.twoPass})
+			if hasDescription := metadata.Description != ""; hasDescription != tt.wantDescription {
+				t.Errorf("description = %q, want the one of article.html: %v", metadata.Description, tt.wantDescription)
+			}
+			pass := ""
+			if metadata.Meta != nil {
+				pass = metadata.Meta.Pass
+			}
+			if pass != tt.wantPass {
+				t.Errorf("pass = %q, want %q", pass, tt.wantPass)
+			}
+
+			var agents []string
+			for _, r := range server.requestsTo(path)[before:] {
+				agents = append(agents, r.UserAgent())
+			}
+			if !slices.Equal(agents, tt.wantAgents) {
+				t.Errorf("requests made as %v, want %v", agents, tt.wantAgents)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// voidElements are the HTML elements without content, which the HTML parser closes by itself
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+// selfClosingTagPattern matches an XML self-closing tag such as <script src="app.js"/>
+var selfClosingTagPattern = regexp.MustCompile(`<([A-Za-z][\w:.-]*)(\s[^<>]*?)?\s*/>`)
+
+// isXHTML reports whether the response is XHTML served as XML
+func isXHTML(contentType string) bool {
+	return strings.Contains(strings.ToLower(contentType), "application/xhtml+xml")
+}
+
+// expandSelfClosingTags rewrites the self-closing tags of elements that have content in HTML,
+// such as <script src="app.js"/> or <title/>, as an opening and a closing tag. The HTML parser
+// ignores the slash, so it would otherwise read the rest of the page, meta tags included,
+// as the content of the element.
+func expandSelfClosingTags(body []byte) []byte {
+	return selfClosingTagPattern.ReplaceAllFunc(body, func(tag []byte) []byte {
+		match := selfClosingTagPattern.FindSubmatch(tag)
+		name := string(match[1])
+		if voidElements[strings.ToLower(localName(name))] {
+			return tag
+		}
+		return []byte("<" + name + string(match[2]) + "></" + name + ">")
+	})
+}
+
+// localName returns a name without its namespace prefix, such as "meta" for "xhtml:meta"
+func localName(name string) string {
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
@@ -0,0 +1,18 @@
+package main
+
+import "testing"
+
+func TestPrefixedXHTML(t *testing.T) {
+	server := newFixtureServer(t)
+
+	metadata := extractPage(t, server.page("prefixed.xhtml"), Options{})
+	if metadata.Title != "Namespaced Markup" {
+		t.Errorf("title = %q, want %q", metadata.Title, "Namespaced Markup")
+	}
+	if metadata.Author != "Ada Example" {
+		t.Errorf("author from the prefixed JSON-LD script = %q, want %q", metadata.Author, "Ada Example")
+	}
+	if metadata.PublishDate != "2024-02-10T00:00:00Z" {
+		t.Errorf("date from the prefixed <time> = %q, want %q", metadata.PublishDate, "2024-02-10T00:00:00Z")
+	}
+}